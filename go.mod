@@ -1,3 +1,8 @@
 module github.com/berkan-cetinkaya/surf
 
 go 1.25.5
+
+require (
+	golang.org/x/net v0.58.0
+	golang.org/x/text v0.41.0
+)
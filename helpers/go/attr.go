@@ -0,0 +1,48 @@
+package surf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidAttr is recorded when an attribute name contains characters
+// outside the allowed set
+var ErrInvalidAttr = errors.New("surf: invalid attribute name")
+
+// SetAttr adds a surface that sets attribute name on the target to value.
+// The value is escaped for the attribute context. Names must start with a
+// letter, '_' or ':' followed by letters, digits, '-', '_', ':' or '.';
+// other names record ErrInvalidAttr and add nothing.
+func (p *Patch) SetAttr(target, name, value string) *Patch {
+	if !validAttrName(name) {
+		return p.fail(fmt.Errorf("%w %q", ErrInvalidAttr, name))
+	}
+
+	return p.add(Surface{
+		Target: target,
+		Mode:   ModeAttr,
+		Attr:   name,
+		Value:  value,
+	})
+}
+
+// SetAttrf is like SetAttr with the value built by fmt.Sprintf
+func (p *Patch) SetAttrf(target, name, format string, args ...any) *Patch {
+	return p.SetAttr(target, name, fmt.Sprintf(format, args...))
+}
+
+func validAttrName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', c == ':':
+		case i > 0 && (c >= '0' && c <= '9' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package surf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSetAttrEscapesValue(t *testing.T) {
+	html, err := NewPatch().SetAttr("#user", "title", `Say "hi" & <wave>`).RenderSafe()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `<surface target="#user" mode="attr" attr="title" value="Say &quot;hi&quot; &amp; &lt;wave&gt;"></surface>`
+	if !strings.Contains(html, expected) {
+		t.Errorf("unexpected render:\n%s", html)
+	}
+}
+
+func TestSetAttrf(t *testing.T) {
+	html := NewPatch().SetAttrf("#bar", "style", "width: %d%%", 42).Render()
+	if !strings.Contains(html, `attr="style" value="width: 42%"`) {
+		t.Errorf("unexpected render:\n%s", html)
+	}
+}
+
+func TestSetAttrInvalidName(t *testing.T) {
+	p := NewPatch().SetAttr("#x", `onclick="alert(1)" data-x`, "v")
+
+	if _, err := p.RenderSafe(); !errors.Is(err, ErrInvalidAttr) {
+		t.Fatalf("expected ErrInvalidAttr, got %v", err)
+	}
+	if len(p.Surfaces()) != 0 {
+		t.Errorf("invalid attribute should not be added")
+	}
+}
+
+func TestSetAttrParseRoundTrip(t *testing.T) {
+	original := NewPatch().SetAttr("#user", "data-name", `O'Brien "Bob"`)
+
+	p, err := Parse(original.Render())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := p.Surfaces()
+	if len(s) != 1 || s[0].Attr != "data-name" || s[0].Value != `O'Brien "Bob"` {
+		t.Errorf("unexpected parsed surface: %+v", s)
+	}
+}
@@ -0,0 +1,102 @@
+package surf
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// binaryVersion is the first byte of MarshalBinary output
+const binaryVersion = 2
+
+// ErrBinaryFormat is returned by UnmarshalBinary for malformed input
+var ErrBinaryFormat = errors.New("surf: malformed binary patch")
+
+// MarshalBinary encodes the resolved surfaces for server-to-server transport,
+// implementing encoding.BinaryMarshaler. The format is a version byte, the
+// surface count, then per surface the target, mode, content, attr, value,
+// slot and content type, each as a uvarint length followed by the bytes,
+// and the seq as a uvarint. Directives, tags and render options are not
+// encoded. The patch is validated with Check first.
+func (p *Patch) MarshalBinary() ([]byte, error) {
+	if err := p.Check(); err != nil {
+		return nil, err
+	}
+	surfaces, err := p.Resolve(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	buf := []byte{binaryVersion}
+	buf = binary.AppendUvarint(buf, uint64(len(surfaces)))
+	for _, s := range surfaces {
+		for _, field := range []string{s.Target, string(s.Mode), s.Content, s.Attr, s.Value, s.Slot, s.contentType} {
+			buf = binary.AppendUvarint(buf, uint64(len(field)))
+			buf = append(buf, field...)
+		}
+		buf = binary.AppendUvarint(buf, s.Seq)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary replaces the surfaces of p with those encoded in data,
+// implementing encoding.BinaryUnmarshaler
+func (p *Patch) UnmarshalBinary(data []byte) error {
+	if p.frozen {
+		return errors.New("surf: cannot unmarshal into the shared Empty patch")
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("%w: empty input", ErrBinaryFormat)
+	}
+	if data[0] != binaryVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrBinaryFormat, data[0])
+	}
+	data = data[1:]
+
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return fmt.Errorf("%w: bad surface count", ErrBinaryFormat)
+	}
+	data = data[n:]
+
+	// Every surface takes at least eight bytes, which bounds the allocation
+	if count > uint64(len(data))/8 {
+		return fmt.Errorf("%w: truncated input", ErrBinaryFormat)
+	}
+
+	surfaces := make([]Surface, 0, count)
+	for i := uint64(0); i < count; i++ {
+		var fields [7]string
+		for j := range fields {
+			length, n := binary.Uvarint(data)
+			if n <= 0 || length > uint64(len(data)-n) {
+				return fmt.Errorf("%w: truncated input", ErrBinaryFormat)
+			}
+			fields[j] = string(data[n : n+int(length)])
+			data = data[n+int(length):]
+		}
+		seq, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("%w: truncated input", ErrBinaryFormat)
+		}
+		data = data[n:]
+
+		surfaces = append(surfaces, Surface{
+			Target:      fields[0],
+			Mode:        Mode(fields[1]),
+			Content:     fields[2],
+			Attr:        fields[3],
+			Value:       fields[4],
+			Slot:        fields[5],
+			contentType: fields[6],
+			Seq:         seq,
+		})
+	}
+	if len(data) != 0 {
+		return fmt.Errorf("%w: trailing bytes", ErrBinaryFormat)
+	}
+
+	p.surfaces = surfaces
+	return nil
+}
@@ -0,0 +1,71 @@
+package surf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	original := NewPatch().
+		AddSurface("#greeting", "<p>Merhaba dünya — こんにちは 👋</p>").
+		AppendSurface("#log", "<li>1</li>").
+		SetAttr("#btn", "title", `"quoted"`).
+		RemoveTarget("#old").
+		AddSlot("header", "h").
+		AddSurfaceSeq("#feed", "<li>new</li>", 42).
+		AddBinary("#icon", []byte{0x89, 'P', 'N', 'G'}, "image/png")
+
+	data, err := original.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	decoded := NewPatch()
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertSurfaces(t, decoded.Surfaces(), original.Surfaces())
+	if decoded.Render() != original.Render() {
+		t.Errorf("render mismatch:\n%s\n%s", decoded.Render(), original.Render())
+	}
+}
+
+func TestBinaryEmptyPatch(t *testing.T) {
+	data, err := NewPatch().MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(data) != 2 || data[0] != binaryVersion {
+		t.Errorf("unexpected encoding: %v", data)
+	}
+
+	decoded := NewPatch().AddSurface("#stale", "x")
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decoded.IsEmpty() {
+		t.Errorf("expected empty patch after decoding")
+	}
+}
+
+func TestBinaryMalformed(t *testing.T) {
+	valid, _ := NewPatch().AddSurface("#a", "content").MarshalBinary()
+
+	for name, data := range map[string][]byte{
+		"empty":     {},
+		"version":   {99, 0},
+		"truncated": valid[:len(valid)-3],
+		"trailing":  append(append([]byte(nil), valid...), 0),
+	} {
+		if err := NewPatch().UnmarshalBinary(data); !errors.Is(err, ErrBinaryFormat) {
+			t.Errorf("%s: expected ErrBinaryFormat, got %v", name, err)
+		}
+	}
+}
+
+func TestBinaryChecks(t *testing.T) {
+	_, err := NewPatch().AddSurface("#a", "x").RemoveTarget("#a").MarshalBinary()
+	if !errors.Is(err, ErrModeConflict) {
+		t.Errorf("expected ErrModeConflict, got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package surf
+
+import "strings"
+
+// SurfaceBuilder assembles the content of one surface from fragments.
+// Create one with Patch.Surface.
+type SurfaceBuilder struct {
+	patch   *Patch
+	target  string
+	content strings.Builder
+}
+
+// Surface starts building a replace surface for target
+func (p *Patch) Surface(target string) *SurfaceBuilder {
+	return &SurfaceBuilder{patch: p, target: target}
+}
+
+// Add appends trusted HTML to the content as is
+func (b *SurfaceBuilder) Add(html string) *SurfaceBuilder {
+	b.content.WriteString(html)
+	return b
+}
+
+// AddText appends text to the content, HTML-escaped
+func (b *SurfaceBuilder) AddText(text string) *SurfaceBuilder {
+	b.content.WriteString(escapeText(text))
+	return b
+}
+
+// Done adds the assembled content to the patch as a single surface and
+// returns the patch
+func (b *SurfaceBuilder) Done() *Patch {
+	return b.patch.AddTrusted(b.target, b.content.String())
+}
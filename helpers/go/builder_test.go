@@ -0,0 +1,37 @@
+package surf
+
+import "testing"
+
+func TestSurfaceBuilder(t *testing.T) {
+	p := NewPatch().
+		Surface("#comment").
+		Add("<p class=\"author\">").
+		AddText("<b>Eve</b> & co").
+		Add("</p>").
+		Done()
+
+	assertSurfaces(t, p.Surfaces(), []Surface{
+		{Target: "#comment", Content: `<p class="author">&lt;b&gt;Eve&lt;/b&gt; &amp; co</p>`},
+	})
+}
+
+func TestSurfaceBuilderChainsBackToPatch(t *testing.T) {
+	p := NewPatch().
+		AddSurface("#a", "1").
+		Surface("#b").AddText("2").Done().
+		AddSurface("#c", "3")
+
+	assertSurfaces(t, p.Surfaces(), []Surface{
+		{Target: "#a", Content: "1"},
+		{Target: "#b", Content: "2"},
+		{Target: "#c", Content: "3"},
+	})
+}
+
+func TestSurfaceBuilderOnEmpty(t *testing.T) {
+	p := Empty().Surface("#a").Add("x").Done()
+	if !Empty().IsEmpty() {
+		t.Fatal("Empty must not be modified")
+	}
+	assertSurfaces(t, p.Surfaces(), []Surface{{Target: "#a", Content: "x"}})
+}
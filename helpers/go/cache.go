@@ -0,0 +1,20 @@
+package surf
+
+// Cache stores rendered surface content by key for AddCached.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(key string) (string, bool)
+	Set(key, val string)
+}
+
+// AddCached adds a surface whose content is cached under cacheKey: a hit
+// uses the cached content without calling gen, a miss calls gen
+// immediately and stores the result in cache
+func (p *Patch) AddCached(target, cacheKey string, gen func() string, cache Cache) *Patch {
+	content, ok := cache.Get(cacheKey)
+	if !ok {
+		content = gen()
+		cache.Set(cacheKey, content)
+	}
+	return p.AddSurface(target, content)
+}
@@ -0,0 +1,44 @@
+package surf
+
+import "testing"
+
+type stubCache map[string]string
+
+func (c stubCache) Get(key string) (string, bool) {
+	val, ok := c[key]
+	return val, ok
+}
+
+func (c stubCache) Set(key, val string) {
+	c[key] = val
+}
+
+func TestAddCachedMiss(t *testing.T) {
+	cache := stubCache{}
+	calls := 0
+	gen := func() string {
+		calls++
+		return "<div>card</div>"
+	}
+
+	p := NewPatch().AddCached("#card", "product:7", gen, cache)
+
+	if calls != 1 {
+		t.Errorf("gen called %d times, want 1", calls)
+	}
+	if cache["product:7"] != "<div>card</div>" {
+		t.Errorf("content not cached: %v", cache)
+	}
+	assertSurfaces(t, p.Surfaces(), []Surface{{Target: "#card", Content: "<div>card</div>"}})
+}
+
+func TestAddCachedHit(t *testing.T) {
+	cache := stubCache{"product:7": "<div>cached</div>"}
+	gen := func() string {
+		t.Error("gen should not be called on a hit")
+		return ""
+	}
+
+	p := NewPatch().AddCached("#card", "product:7", gen, cache)
+	assertSurfaces(t, p.Surfaces(), []Surface{{Target: "#card", Content: "<div>cached</div>"}})
+}
@@ -0,0 +1,63 @@
+package surf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrModeConflict is returned by Check and RenderSafe when the same target
+// gets surfaces whose modes cannot both apply, such as a replace and a
+// remove, or anything after a remove
+var ErrModeConflict = errors.New("surf: conflicting modes")
+
+// ErrSlotAndTarget is returned by Check and RenderSafe for a surface that
+// sets both a slot and a target
+var ErrSlotAndTarget = errors.New("surf: surface has both slot and target")
+
+// Check validates the patch without rendering it: it returns the first
+// error recorded while building, an unknown alias, a surface with both a
+// slot and a target, or a mode conflict.
+// Generators are not run, so their errors surface only at render time.
+func (p *Patch) Check() error {
+	if p.err != nil {
+		return p.err
+	}
+
+	// modes maps each target to the first mode applied to it; removed marks
+	// targets already removed
+	modes := make(map[string]Mode)
+	removed := make(map[string]bool)
+	for _, s := range p.surfaces {
+		if s.Slot != "" {
+			if s.Target != "" {
+				return fmt.Errorf("%w: slot %q, target %q", ErrSlotAndTarget, s.Slot, s.Target)
+			}
+			continue
+		}
+		target, err := resolveTarget(s.Target)
+		if err != nil {
+			return err
+		}
+
+		mode := s.Mode
+		if s.isReplace() {
+			mode = ModeReplace
+		}
+		switch {
+		case removed[target] && mode != ModeRemove:
+			return fmt.Errorf("%w for %q: %s after remove", ErrModeConflict, target, mode)
+		case mode == ModeRemove && modes[target] != "" && !removed[target]:
+			return fmt.Errorf("%w for %q: %s and remove", ErrModeConflict, target, modes[target])
+		}
+
+		if mode == ModeRemove {
+			removed[target] = true
+		}
+		if s.appliesInside() || mode == ModeRemove {
+			if modes[target] == "" {
+				modes[target] = mode
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package surf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckReplaceAndRemoveConflict(t *testing.T) {
+	p := NewPatch().
+		AddSurface("#item", "new").
+		RemoveTarget("#item")
+
+	err := p.Check()
+	if !errors.Is(err, ErrModeConflict) {
+		t.Fatalf("expected ErrModeConflict, got %v", err)
+	}
+	if want := `surf: conflicting modes for "#item": replace and remove`; err.Error() != want {
+		t.Errorf("got %q, want %q", err, want)
+	}
+	if _, err := p.RenderSafe(); !errors.Is(err, ErrModeConflict) {
+		t.Errorf("RenderSafe should report the conflict, got %v", err)
+	}
+}
+
+func TestCheckAppendAfterRemoveConflict(t *testing.T) {
+	err := NewPatch().
+		RemoveTarget("#list").
+		AppendSurface("#list", "<li>x</li>").
+		Check()
+	if !errors.Is(err, ErrModeConflict) {
+		t.Fatalf("expected ErrModeConflict, got %v", err)
+	}
+}
+
+func TestCheckAppendAndPrependAllowed(t *testing.T) {
+	p := NewPatch().
+		AppendSurface("#list", "<li>last</li>").
+		PrependSurface("#list", "<li>first</li>").
+		RemoveTarget("#other")
+
+	if err := p.Check(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := p.RenderSafe(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package surf
+
+import "sync"
+
+// Coalescer merges patches queued in quick succession into one.
+// It is safe for concurrent use.
+type Coalescer struct {
+	mu     sync.Mutex
+	queued *Patch
+}
+
+// NewCoalescer creates a new Coalescer
+func NewCoalescer() *Coalescer {
+	return &Coalescer{}
+}
+
+// Add queues the surfaces and directives of p. A later directive with the
+// same tag and key replaces an earlier one, as within a patch, and the
+// first recorded error is kept. Settings such as status and options come
+// from the first patch queued since the last flush; the root seq is not
+// kept.
+func (c *Coalescer) Add(p *Patch) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.queued == nil {
+		c.queued = p.derive()
+	} else if c.queued.err == nil {
+		c.queued.err = p.err
+	}
+	c.queued.surfaces = coalesce(c.queued.surfaces, p.surfaces)
+	for _, d := range p.directives {
+		c.queued.setDirective(d)
+	}
+}
+
+// Flush returns the queued surfaces and directives as a single patch and
+// empties the queue. A replace surface supersedes every earlier surface
+// applied inside the same target; append and prepend surfaces keep their
+// relative order.
+func (c *Coalescer) Flush() *Patch {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := c.queued
+	c.queued = nil
+	if p == nil {
+		return NewPatch()
+	}
+	return p
+}
+
+// coalesce appends next to queued, dropping queued surfaces made obsolete
+// by a later replace of the same target
+func coalesce(queued, next []Surface) []Surface {
+	for _, s := range next {
+		if s.isReplace() {
+			kept := queued[:0]
+			for _, q := range queued {
+				if q.Target == s.Target && q.Slot == s.Slot && q.appliesInside() {
+					continue
+				}
+				kept = append(kept, q)
+			}
+			queued = kept
+		}
+		queued = append(queued, s)
+	}
+	return queued
+}
@@ -0,0 +1,76 @@
+package surf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCoalescer(t *testing.T) {
+	c := NewCoalescer()
+	c.Add(NewPatch().AddSurface("#count", "1").AppendSurface("#log", "<li>a</li>"))
+	c.Add(NewPatch().AddSurface("#count", "2").AddSurface("#status", "busy"))
+	c.Add(NewPatch().AppendSurface("#log", "<li>b</li>").PrependSurface("#log", "<li>0</li>"))
+	c.Add(NewPatch().AddSurface("#count", "3"))
+
+	got := c.Flush().Surfaces()
+	want := []Surface{
+		{Target: "#log", Content: "<li>a</li>", Mode: ModeAppend},
+		{Target: "#status", Content: "busy"},
+		{Target: "#log", Content: "<li>b</li>", Mode: ModeAppend},
+		{Target: "#log", Content: "<li>0</li>", Mode: ModePrepend},
+		{Target: "#count", Content: "3"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("expected %d surfaces, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i].Target != want[i].Target || got[i].Content != want[i].Content || got[i].Mode != want[i].Mode {
+			t.Errorf("surface %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+
+	if len(c.Flush().Surfaces()) != 0 {
+		t.Errorf("Flush should empty the queue")
+	}
+}
+
+func TestCoalescerReplaceDropsEarlierAppends(t *testing.T) {
+	c := NewCoalescer()
+	c.Add(NewPatch().AppendSurface("#list", "<li>old</li>").InsertAfter("#list", "<hr>"))
+	c.Add(NewPatch().AddSurface("#list", "<li>fresh</li>"))
+
+	got := c.Flush().Surfaces()
+	if len(got) != 2 || got[0].Mode != ModeAfter || got[1].Content != "<li>fresh</li>" {
+		t.Errorf("unexpected coalesced surfaces: %+v", got)
+	}
+}
+
+func TestCoalescerKeepsDirectivesAndErrors(t *testing.T) {
+	c := NewCoalescer()
+	c.Add(NewPatch(WithChecksums()).WithStatus(202).AddSurface("#a", "1").Ack("a1").AddState("k", 1))
+	c.Add(NewPatch().AddState("k", 2).SetMeta("description", "d").AddState("bad", func() {}))
+	c.Add(NewPatch().AddComponent("#b", "missing", nil))
+
+	p := c.Flush()
+	if err := p.Err(); err == nil || !strings.Contains(err.Error(), `state "bad"`) {
+		t.Errorf("expected the first error to be kept, got %v", err)
+	}
+	if p.statusCode() != 202 || !p.opts.checksums {
+		t.Errorf("settings of the first patch should be kept")
+	}
+
+	html := p.Render()
+	for _, want := range []string{`<ack id="a1"></ack>`, `<state key="k">2</state>`, `<meta name="description" content="d">`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("missing %s in:\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, `<state key="k">1</state>`) {
+		t.Errorf("later state should replace the earlier one:\n%s", html)
+	}
+
+	if !c.Flush().IsEmpty() {
+		t.Error("Flush should empty the queue")
+	}
+}
@@ -0,0 +1,84 @@
+package surf
+
+// ComponentPatch is a patch scoped to a component root, with child
+// components nested below it. Targets inside a component are relative to
+// its root; Flatten turns the tree into one patch with full selectors.
+type ComponentPatch struct {
+	root     string
+	patch    *Patch
+	children []*ComponentPatch
+}
+
+// NewComponentPatch creates a ComponentPatch for the component at
+// rootSelector. An empty root leaves targets unprefixed, for a top-level
+// tree of components.
+func NewComponentPatch(rootSelector string, opts ...Option) *ComponentPatch {
+	return &ComponentPatch{root: rootSelector, patch: NewPatch(opts...)}
+}
+
+// Patch returns the component's own patch, whose surface targets are
+// relative to the component root. The empty target "" selects the root
+// itself.
+func (cp *ComponentPatch) Patch() *Patch {
+	return cp.patch
+}
+
+// Child creates a sub-component rooted at rootSelector within cp
+func (cp *ComponentPatch) Child(rootSelector string) *ComponentPatch {
+	child := &ComponentPatch{root: rootSelector, patch: NewPatch()}
+	cp.children = append(cp.children, child)
+	return child
+}
+
+// Flatten returns a single patch with the surfaces and directives of cp and
+// all of its descendants, depth first, each target prefixed by the roots of
+// its ancestors as a descendant selector. Aliases in roots and targets are
+// resolved before prefixing; slot surfaces are left unscoped. Roots and
+// targets must be single selectors, not lists. The settings of cp's own
+// patch are kept.
+func (cp *ComponentPatch) Flatten() *Patch {
+	flat := cp.patch.derive()
+	return cp.flattenInto(flat, "")
+}
+
+func (cp *ComponentPatch) flattenInto(flat *Patch, scope string) *Patch {
+	root, err := resolveTarget(cp.root)
+	if err != nil {
+		flat = flat.fail(err)
+	}
+	scope = scopeSelector(scope, root)
+
+	for _, s := range cp.patch.surfaces {
+		if s.Slot == "" {
+			target, err := resolveTarget(s.Target)
+			if err != nil {
+				flat = flat.fail(err)
+			}
+			s.Target = scopeSelector(scope, target)
+		}
+		flat.surfaces = append(flat.surfaces, s)
+	}
+	for _, d := range cp.patch.directives {
+		flat = flat.setDirective(d)
+	}
+	if cp.patch.err != nil {
+		flat = flat.fail(cp.patch.err)
+	}
+
+	for _, child := range cp.children {
+		flat = child.flattenInto(flat, scope)
+	}
+	return flat
+}
+
+// scopeSelector returns selector as a descendant of scope
+func scopeSelector(scope, selector string) string {
+	switch {
+	case scope == "":
+		return selector
+	case selector == "":
+		return scope
+	default:
+		return scope + " " + selector
+	}
+}
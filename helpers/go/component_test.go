@@ -0,0 +1,70 @@
+package surf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestComponentPatchFlatten(t *testing.T) {
+	page := NewComponentPatch("")
+	page.Patch().AddSurface("#title", "Orders")
+
+	list := page.Child("#orders")
+	list.Patch().AppendSurface("ul", "<li>new</li>")
+
+	row := list.Child("[data-id=\"7\"]")
+	row.Patch().
+		AddSurface(".status", "shipped").
+		SetAttr("", "class", "done")
+
+	sidebar := page.Child("#sidebar")
+	sidebar.Patch().AddSurface(".count", "3")
+
+	assertSurfaces(t, page.Flatten().Surfaces(), []Surface{
+		{Target: "#title", Content: "Orders"},
+		{Target: "#orders ul", Content: "<li>new</li>", Mode: ModeAppend},
+		{Target: "#orders [data-id=\"7\"] .status", Content: "shipped"},
+		{Target: "#orders [data-id=\"7\"]", Mode: ModeAttr, Attr: "class", Value: "done"},
+		{Target: "#sidebar .count", Content: "3"},
+	})
+}
+
+func TestComponentPatchFlattenErrors(t *testing.T) {
+	root := NewComponentPatch("#app")
+	root.Child("#widget").Patch().AddSurface("@missing", "x")
+
+	if err := root.Flatten().Err(); !errors.Is(err, ErrUnknownTarget) {
+		t.Errorf("expected ErrUnknownTarget, got %v", err)
+	}
+}
+
+func TestComponentPatchFlattenSlots(t *testing.T) {
+	root := NewComponentPatch("#app")
+	root.Child("#widget").Patch().AddSlot("header", "h")
+
+	p := root.Flatten()
+	if _, err := p.RenderSafe(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertSurfaces(t, p.Surfaces(), []Surface{{Slot: "header", Content: "h"}})
+}
+
+func TestComponentPatchFlattenAliasRoots(t *testing.T) {
+	RegisterTarget("@card", "#card-7")
+	t.Cleanup(func() { unregisterTarget("@card") })
+
+	root := NewComponentPatch("#app")
+	root.Child("@card").Patch().AddSurface(".title", "Hi")
+
+	p := root.Flatten()
+	if err := p.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertSurfaces(t, p.Surfaces(), []Surface{{Target: "#app #card-7 .title", Content: "Hi"}})
+
+	missing := NewComponentPatch("@missing")
+	missing.Patch().AddSurface(".title", "Hi")
+	if err := missing.Flatten().Err(); !errors.Is(err, ErrUnknownTarget) {
+		t.Errorf("expected ErrUnknownTarget, got %v", err)
+	}
+}
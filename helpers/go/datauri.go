@@ -0,0 +1,28 @@
+package surf
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// AddBinary adds a surface embedding data as a base64 data URI of type
+// mime: an <img> for image types and an <object> otherwise. The surface
+// carries a content-type attribute with mime.
+// Base64 grows the payload by a third and the data cannot be cached apart
+// from the patch, so keep it to small assets such as icons.
+func (p *Patch) AddBinary(target string, data []byte, mime string) *Patch {
+	uri := escapeAttr("data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data))
+
+	var content string
+	if strings.HasPrefix(mime, "image/") {
+		content = `<img src="` + uri + `">`
+	} else {
+		content = `<object type="` + escapeAttr(mime) + `" data="` + uri + `"></object>`
+	}
+
+	return p.add(Surface{
+		Target:      target,
+		Content:     content,
+		contentType: mime,
+	})
+}
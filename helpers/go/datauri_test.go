@@ -0,0 +1,38 @@
+package surf
+
+import "testing"
+
+// pngHeader is the 8-byte PNG signature
+var pngHeader = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+func TestAddBinaryImage(t *testing.T) {
+	html := NewPatch().AddBinary("#avatar", pngHeader, "image/png").Render()
+
+	expected := "<d-patch>\n" +
+		"  <surface target=\"#avatar\" content-type=\"image/png\"><img src=\"data:image/png;base64,iVBORw0KGgo=\"></surface>\n" +
+		"</d-patch>"
+	if html != expected {
+		t.Errorf("unexpected render:\n%s", html)
+	}
+}
+
+func TestAddBinaryOther(t *testing.T) {
+	p := NewPatch().AddBinary("#doc", []byte("%PDF"), "application/pdf")
+
+	want := `<object type="application/pdf" data="data:application/pdf;base64,JVBERg=="></object>`
+	if got := p.Surfaces()[0].Content; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseBinary(t *testing.T) {
+	original := NewPatch().AddBinary("#avatar", pngHeader, "image/png")
+
+	p, err := Parse(original.Render())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Render() != original.Render() {
+		t.Errorf("round trip mismatch:\n%s", p.Render())
+	}
+}
@@ -0,0 +1,75 @@
+package surf
+
+import "context"
+
+// DiffAgainst returns a patch with only the surfaces of p whose target is
+// updated differently in prev, matching targets exactly after alias
+// resolution. Targets with several surfaces, such as repeated appends, are
+// compared and sent as a whole. Under WithRemoveMissing, targets updated in
+// prev but not in p get a remove surface.
+func (p *Patch) DiffAgainst(prev *Patch) *Patch {
+	next := p.derive()
+
+	current, err := p.Resolve(context.Background())
+	if err != nil {
+		return next.fail(err)
+	}
+	previous, err := prev.Resolve(context.Background())
+	if err != nil {
+		return next.fail(err)
+	}
+
+	before := groupByTarget(previous)
+	after := groupByTarget(current)
+	for _, s := range current {
+		if !sameSurfaces(before[surfaceKey(s)], after[surfaceKey(s)]) {
+			next.surfaces = append(next.surfaces, s)
+		}
+	}
+
+	if p.opts.removeMissing {
+		seen := make(map[string]bool)
+		for _, s := range previous {
+			if _, ok := after[surfaceKey(s)]; ok || seen[s.Target] || s.Slot != "" {
+				continue
+			}
+			seen[s.Target] = true
+			next = next.RemoveTarget(s.Target)
+		}
+	}
+	return next
+}
+
+// groupByTarget collects surfaces by surfaceKey, keeping their order
+func groupByTarget(surfaces []Surface) map[string][]Surface {
+	groups := make(map[string][]Surface)
+	for _, s := range surfaces {
+		groups[surfaceKey(s)] = append(groups[surfaceKey(s)], s)
+	}
+	return groups
+}
+
+// surfaceKey identifies what a surface updates: its target or its slot
+func surfaceKey(s Surface) string {
+	if s.Slot != "" {
+		return "slot:" + s.Slot
+	}
+	return s.Target
+}
+
+// sameSurfaces reports whether a and b apply the same updates
+func sameSurfaces(a, b []Surface) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		sameMode := a[i].Mode == b[i].Mode || a[i].isReplace() && b[i].isReplace()
+		if !sameMode ||
+			a[i].Content != b[i].Content ||
+			a[i].Attr != b[i].Attr ||
+			a[i].Value != b[i].Value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,43 @@
+package surf
+
+import "testing"
+
+func TestDiffAgainstChangedAndUnchanged(t *testing.T) {
+	prev := NewPatch().
+		AddSurface("#count", "1").
+		AddSurface("#title", "Cart")
+	next := NewPatch().
+		AddSurface("#count", "2").
+		AddSurface("#title", "Cart")
+
+	assertSurfaces(t, next.DiffAgainst(prev).Surfaces(), []Surface{
+		{Target: "#count", Content: "2"},
+	})
+}
+
+func TestDiffAgainstNewTargetAndMode(t *testing.T) {
+	prev := NewPatch().AddSurface("#list", "a")
+	next := NewPatch().
+		AppendSurface("#list", "a").
+		AddSurface("#footer", "f")
+
+	assertSurfaces(t, next.DiffAgainst(prev).Surfaces(), []Surface{
+		{Target: "#list", Content: "a", Mode: ModeAppend},
+		{Target: "#footer", Content: "f"},
+	})
+}
+
+func TestDiffAgainstRemovedTargets(t *testing.T) {
+	prev := NewPatch().
+		AddSurface("#a", "1").
+		AddSurface("#gone", "x")
+
+	if got := NewPatch().AddSurface("#a", "1").DiffAgainst(prev); !got.IsEmpty() {
+		t.Errorf("removals should be opt-in, got %v", got.Surfaces())
+	}
+
+	got := NewPatch(WithRemoveMissing()).AddSurface("#a", "1").DiffAgainst(prev)
+	assertSurfaces(t, got.Surfaces(), []Surface{
+		{Target: "#gone", Mode: ModeRemove},
+	})
+}
@@ -0,0 +1,104 @@
+package surf
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// directive is a non-surface element of a patch, such as <state>.
+// Directives with the same tag and key replace each other.
+type directive struct {
+	tag     string
+	key     string
+	attrs   []attribute
+	content string
+}
+
+// voidDirectives are directive tags rendered without content or end tag
+var voidDirectives = map[string]bool{"meta": true}
+
+type attribute struct {
+	name  string
+	value string
+}
+
+// setDirective adds d, replacing an earlier directive with the same tag and key
+func (p *Patch) setDirective(d directive) *Patch {
+	p = p.mutable()
+	for i, existing := range p.directives {
+		if existing.tag == d.tag && existing.key == d.key {
+			p.directives[i] = d
+			return p
+		}
+	}
+	p.directives = append(p.directives, d)
+	return p
+}
+
+func writeDirective(sb *strings.Builder, d directive, o *options) {
+	sb.WriteString("  <" + d.tag)
+	for _, a := range d.attrs {
+		sb.WriteString(o.attr(a.name, escapeAttr(a.value)))
+	}
+	if voidDirectives[d.tag] {
+		sb.WriteString(">" + o.eol())
+		return
+	}
+	sb.WriteString(fmt.Sprintf(">%s</%s>", d.content, d.tag) + o.eol())
+}
+
+// AddState adds a <state key="..."> directive carrying value as JSON for
+// client-side hydration. A later call with the same key replaces the
+// earlier value. Marshaling errors are recorded and reported by RenderSafe.
+func (p *Patch) AddState(key string, value any) *Patch {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return p.fail(fmt.Errorf("surf: state %q: %w", key, err))
+	}
+
+	return p.setDirective(directive{
+		tag:     "state",
+		key:     key,
+		attrs:   []attribute{{"key", key}},
+		content: string(data),
+	})
+}
+
+// SetMeta adds a <meta name="..." content="..."> directive that the client
+// merges into <head>, replacing any meta tag with the same name. A later
+// call with the same name overwrites the earlier one.
+func (p *Patch) SetMeta(name, content string) *Patch {
+	return p.setDirective(directive{
+		tag:   "meta",
+		key:   "name:" + name,
+		attrs: []attribute{{"name", name}, {"content", content}},
+	})
+}
+
+// SetMetaProperty is like SetMeta for property-based tags such as Open
+// Graph's og:title
+func (p *Patch) SetMetaProperty(property, content string) *Patch {
+	return p.setDirective(directive{
+		tag:   "meta",
+		key:   "property:" + property,
+		attrs: []attribute{{"property", property}, {"content", content}},
+	})
+}
+
+// Ack creates a new Patch with only an <ack id="..."> directive, confirming
+// to the client that the action with actionID was processed so it can
+// settle its optimistic update
+func Ack(actionID string) *Patch {
+	return NewPatch().Ack(actionID)
+}
+
+// Ack adds an <ack id="..."> directive for actionID to the patch.
+// Acknowledging the same id twice sends a single ack.
+func (p *Patch) Ack(actionID string) *Patch {
+	return p.setDirective(directive{
+		tag:   "ack",
+		key:   actionID,
+		attrs: []attribute{{"id", actionID}},
+	})
+}
@@ -0,0 +1,138 @@
+package surf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAddStateString(t *testing.T) {
+	html := NewPatch().AddState("user.name", `Ada "the first"`).Render()
+	if !strings.Contains(html, `<state key="user.name">"Ada \"the first\""</state>`) {
+		t.Errorf("unexpected render: %s", html)
+	}
+}
+
+func TestAddStateStruct(t *testing.T) {
+	type cart struct {
+		Items int    `json:"items"`
+		Note  string `json:"note"`
+	}
+	html := NewPatch().
+		AddSurface("#cart", "<b>2</b>").
+		AddState(`cart"x`, cart{Items: 2, Note: "<b>"}).
+		Render()
+
+	expected := "<d-patch>\n" +
+		"  <surface target=\"#cart\"><b>2</b></surface>\n" +
+		"  <state key=\"cart&quot;x\">{\"items\":2,\"note\":\"\\u003cb\\u003e\"}</state>\n" +
+		"</d-patch>"
+	if html != expected {
+		t.Errorf("unexpected render:\n%s", html)
+	}
+}
+
+func TestAddStateDuplicateKey(t *testing.T) {
+	p := NewPatch().AddState("count", 1).AddState("other", true).AddState("count", 2)
+	html := p.Render()
+
+	if strings.Count(html, `<state key="count">`) != 1 || !strings.Contains(html, `<state key="count">2</state>`) {
+		t.Errorf("expected last value to win: %s", html)
+	}
+	if p.IsEmpty() {
+		t.Errorf("patch with state should not be empty")
+	}
+}
+
+func TestAddStateMarshalError(t *testing.T) {
+	_, err := NewPatch().AddState("bad", make(chan int)).RenderSafe()
+	if err == nil || !strings.Contains(err.Error(), `state "bad"`) {
+		t.Errorf("expected marshal error naming the key, got %v", err)
+	}
+}
+
+func TestParseDirectives(t *testing.T) {
+	original := NewPatch().AddSurface("#a", "x").AddState("k", map[string]int{"n": 1})
+
+	p, err := Parse(original.Render())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Render() != original.Render() {
+		t.Errorf("round trip mismatch:\n%s", p.Render())
+	}
+}
+
+func TestSetMeta(t *testing.T) {
+	html := NewPatch().SetMeta("description", `Tips & "tricks"`).Render()
+	if !strings.Contains(html, `  <meta name="description" content="Tips &amp; &quot;tricks&quot;">`+"\n") {
+		t.Errorf("unexpected render: %s", html)
+	}
+}
+
+func TestSetMetaProperty(t *testing.T) {
+	html := NewPatch().SetMetaProperty("og:title", "<Surf>").Render()
+	if !strings.Contains(html, `<meta property="og:title" content="&lt;Surf&gt;">`) {
+		t.Errorf("unexpected render: %s", html)
+	}
+}
+
+func TestSetMetaOverwrite(t *testing.T) {
+	p := NewPatch().
+		SetMeta("description", "old").
+		SetMetaProperty("description", "property, not name").
+		SetMeta("description", "new")
+
+	html := p.Render()
+	if strings.Count(html, `name="description"`) != 1 || !strings.Contains(html, `content="new"`) {
+		t.Errorf("expected overwrite: %s", html)
+	}
+	if !strings.Contains(html, `property="description"`) {
+		t.Errorf("name and property tags are distinct: %s", html)
+	}
+
+	parsed, err := Parse(html)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.SetMeta("description", "newest").Render() != p.SetMeta("description", "newest").Render() {
+		t.Errorf("parsed meta directives should keep their keys")
+	}
+}
+
+func TestAck(t *testing.T) {
+	p := Ack(`op-1"`)
+
+	if p.IsEmpty() {
+		t.Error("an ack patch is not empty")
+	}
+	if len(p.Surfaces()) != 0 {
+		t.Errorf("an ack patch has no surfaces, got %v", p.Surfaces())
+	}
+	expected := "<d-patch>\n" +
+		"  <ack id=\"op-1&quot;\"></ack>\n" +
+		"</d-patch>"
+	if html := p.Render(); html != expected {
+		t.Errorf("unexpected render:\n%s", html)
+	}
+}
+
+func TestAckAttached(t *testing.T) {
+	p := NewPatch().AddSurface("#cart", "2 items").Ack("add-7").Ack("add-7")
+
+	expected := "<d-patch>\n" +
+		"  <surface target=\"#cart\">2 items</surface>\n" +
+		"  <ack id=\"add-7\"></ack>\n" +
+		"</d-patch>"
+	html := p.Render()
+	if html != expected {
+		t.Errorf("unexpected render:\n%s", html)
+	}
+
+	parsed, err := Parse(html)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.Ack("add-7").Render() != html {
+		t.Errorf("parsed ack should keep its id as key:\n%s", parsed.Render())
+	}
+}
@@ -0,0 +1,41 @@
+package surf
+
+import (
+	"net/http"
+	"strings"
+)
+
+// FeaturesHeader is the request header in which the client lists the
+// features it supports, as comma-separated case-insensitive tokens,
+// e.g. "X-Surf-Features: morph, view-transitions"
+const FeaturesHeader = "X-Surf-Features"
+
+// FeatureMorph is the feature token for ModeMorph support
+const FeatureMorph = "morph"
+
+// AddAdaptive adds a morph surface when the request advertises the morph
+// feature and a plain replace surface otherwise. Under StrictEscaping the
+// content is escaped as text either way.
+func (p *Patch) AddAdaptive(target, content string, r *http.Request) *Patch {
+	content = strictText(content)
+	if !ClientSupports(r, FeatureMorph) {
+		return p.AddTrusted(target, content)
+	}
+	return p.add(Surface{
+		Target:  target,
+		Content: content,
+		Mode:    ModeMorph,
+	})
+}
+
+// ClientSupports reports whether the request lists feature in FeaturesHeader
+func ClientSupports(r *http.Request, feature string) bool {
+	for _, value := range r.Header.Values(FeaturesHeader) {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), feature) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package surf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAddAdaptiveMorphCapable(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(FeaturesHeader, "view-transitions, Morph")
+
+	html := NewPatch().AddAdaptive("#list", "<li>1</li>", r).Render()
+	if !strings.Contains(html, `<surface target="#list" mode="morph"><li>1</li></surface>`) {
+		t.Errorf("expected morph surface: %s", html)
+	}
+}
+
+func TestAddAdaptivePlainClient(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(FeaturesHeader, "view-transitions")
+
+	html := NewPatch().AddAdaptive("#list", "<li>1</li>", r).Render()
+	if !strings.Contains(html, `<surface target="#list"><li>1</li></surface>`) {
+		t.Errorf("expected replace surface: %s", html)
+	}
+}
+
+func TestAddAdaptiveStrictEscaping(t *testing.T) {
+	StrictEscaping = true
+	defer func() { StrictEscaping = false }()
+
+	morph := httptest.NewRequest(http.MethodGet, "/", nil)
+	morph.Header.Set(FeaturesHeader, "morph")
+	plain := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	for _, r := range []*http.Request{morph, plain} {
+		content := NewPatch().AddAdaptive("#list", "<b>1</b>", r).Surfaces()[0].Content
+		if content != "&lt;b&gt;1&lt;/b&gt;" {
+			t.Errorf("expected escaped content for both paths, got %q", content)
+		}
+	}
+}
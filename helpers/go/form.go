@@ -0,0 +1,53 @@
+package surf
+
+import (
+	"sort"
+	"strings"
+)
+
+// FormErrors adds a surface per field replacing the content of its error
+// container with the escaped message, and marks the field itself with
+// aria-invalid="true" unless the patch uses WithNoAriaInvalid. Fields are
+// processed in name order.
+//
+// For a field named "email" the error container is "#email-error" and the
+// field is `<formTarget> [name="email"]`. formTarget may be an alias.
+func (p *Patch) FormErrors(formTarget string, fieldErrors map[string]string) *Patch {
+	form, err := resolveTarget(formTarget)
+	if err != nil {
+		p = p.fail(err)
+	}
+
+	fields := make([]string, 0, len(fieldErrors))
+	for field := range fieldErrors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		p = p.AddTrusted("#"+cssEscapeIdent(field)+"-error", escapeText(fieldErrors[field]))
+		if !p.opts.noAriaInvalid {
+			p = p.SetAttr(form+` [name="`+cssEscapeString(field)+`"]`, "aria-invalid", "true")
+		}
+	}
+	return p
+}
+
+// cssEscapeIdent backslash-escapes characters not allowed in a CSS identifier
+func cssEscapeIdent(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 0x7f {
+			sb.WriteRune(r)
+			continue
+		}
+		sb.WriteByte('\\')
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// cssEscapeString escapes s for use inside a double-quoted CSS string
+func cssEscapeString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
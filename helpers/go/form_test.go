@@ -0,0 +1,50 @@
+package surf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFormErrors(t *testing.T) {
+	p := NewPatch().FormErrors("#signup", map[string]string{
+		"name":  "Name is required",
+		"email": `"bob@" is not <valid>`,
+	})
+
+	assertSurfaces(t, p.Surfaces(), []Surface{
+		{Target: "#email-error", Content: "&quot;bob@&quot; is not &lt;valid&gt;"},
+		{Target: `#signup [name="email"]`, Mode: ModeAttr, Attr: "aria-invalid", Value: "true"},
+		{Target: "#name-error", Content: "Name is required"},
+		{Target: `#signup [name="name"]`, Mode: ModeAttr, Attr: "aria-invalid", Value: "true"},
+	})
+}
+
+func TestFormErrorsEscapesFieldNames(t *testing.T) {
+	got := NewPatch().FormErrors("form", map[string]string{"user[email]": "bad"}).Surfaces()
+
+	if got[0].Target != `#user\[email\]-error` || got[1].Target != `form [name="user[email]"]` {
+		t.Errorf("unexpected targets: %q, %q", got[0].Target, got[1].Target)
+	}
+}
+
+func TestFormErrorsAliasTarget(t *testing.T) {
+	RegisterTarget("@signup", "#signup")
+	t.Cleanup(func() { unregisterTarget("@signup") })
+
+	p := NewPatch().FormErrors("@signup", map[string]string{"email": "bad"})
+	if _, err := p.RenderSafe(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := p.Surfaces()[1].Target; got != `#signup [name="email"]` {
+		t.Errorf("unexpected field target %q", got)
+	}
+
+	if err := NewPatch().FormErrors("@missing", map[string]string{"email": "bad"}).Err(); !errors.Is(err, ErrUnknownTarget) {
+		t.Errorf("expected ErrUnknownTarget, got %v", err)
+	}
+}
+
+func TestFormErrorsNoAriaInvalid(t *testing.T) {
+	p := NewPatch(WithNoAriaInvalid()).FormErrors("#signup", map[string]string{"email": "bad"})
+	assertSurfaces(t, p.Surfaces(), []Surface{{Target: "#email-error", Content: "bad"}})
+}
@@ -0,0 +1,218 @@
+package surf
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the Content-Type header for patch responses
+const ContentType = "text/html; charset=utf-8"
+
+// WithStatus sets the HTTP status used by WriteResponse (default 200)
+func (p *Patch) WithStatus(code int) *Patch {
+	p = p.mutable()
+	p.status = code
+	return p
+}
+
+// WithRetryAfter sets the delay reported in the Retry-After header, in whole
+// seconds rounded up, typically with a 429 or 503 status. Without it no
+// Retry-After header is sent, whatever the status.
+func (p *Patch) WithRetryAfter(d time.Duration) *Patch {
+	p = p.mutable()
+	p.retryAfter = d
+	return p
+}
+
+func (p *Patch) statusCode() int {
+	if p.status == 0 {
+		return http.StatusOK
+	}
+	return p.status
+}
+
+// writeHeader sets the patch headers on w and writes the status
+func (p *Patch) writeHeader(w http.ResponseWriter) {
+	status := p.statusCode()
+	p.setHeaders(w.Header())
+	w.WriteHeader(status)
+}
+
+// setHeaders sets the headers for a patch response
+func (p *Patch) setHeaders(h http.Header) {
+	h.Set("Content-Type", ContentType)
+	if p.retryAfter > 0 {
+		seconds := int64((p.retryAfter + time.Second - 1) / time.Second)
+		h.Set("Retry-After", strconv.FormatInt(seconds, 10))
+	}
+	if timing := p.serverTiming(); timing != "" {
+		h.Set("Server-Timing", timing)
+	}
+}
+
+// WriteResponse renders the patch and writes it to w with the patch
+// Content-Type and status. Nothing is written if rendering fails.
+func (p *Patch) WriteResponse(w http.ResponseWriter) error {
+	start := writeStart()
+	html, err := p.RenderSafe()
+	if err != nil {
+		return err
+	}
+
+	p.writeHeader(w)
+	n, err := w.Write([]byte(html))
+	notifyWrite(start, w, p.statusCode(), n)
+	return err
+}
+
+// WriteOrNoContent writes a 204 No Content response with no body and no
+// patch headers when the patch is empty (see IsEmpty), ignoring its status,
+// so the client can skip the swap. A non-empty patch is written with
+// WriteResponse. Errors recorded while building are reported either way.
+func (p *Patch) WriteOrNoContent(w http.ResponseWriter) error {
+	if !p.IsEmpty() {
+		return p.WriteResponse(w)
+	}
+	if err := p.Check(); err != nil {
+		return err
+	}
+
+	start := writeStart()
+	w.WriteHeader(http.StatusNoContent)
+	notifyWrite(start, w, http.StatusNoContent, 0)
+	return nil
+}
+
+// WriteChunkedWithTrailer streams the patch to w one surface per chunk and
+// reports the number of surfaces in an X-Surface-Count trailer.
+// Writers that cannot flush cannot stream, so the patch is then written
+// with WriteResponse and no trailer is sent. As with RenderSafe, nothing is
+// written if Check reports a problem.
+func (p *Patch) WriteChunkedWithTrailer(w http.ResponseWriter) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return p.WriteResponse(w)
+	}
+
+	start := writeStart()
+	if err := p.Check(); err != nil {
+		return err
+	}
+	surfaces, err := p.Resolve(context.Background())
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Trailer", "X-Surface-Count")
+	p.writeHeader(w)
+
+	written := 0
+	defer func() { notifyWrite(start, w, p.statusCode(), written) }()
+	write := func(s string) error {
+		n, err := w.Write([]byte(s))
+		written += n
+		return err
+	}
+
+	if p.IsEmpty() {
+		if err := write(p.openTag() + patchClose); err != nil {
+			return err
+		}
+	} else {
+		if err := write(p.openTag() + p.opts.eol()); err != nil {
+			return err
+		}
+		for _, s := range surfaces {
+			var sb strings.Builder
+			writeSurface(&sb, s, &p.opts)
+			if err := write(sb.String()); err != nil {
+				return err
+			}
+			flusher.Flush()
+		}
+
+		var sb strings.Builder
+		for _, d := range p.directives {
+			writeDirective(&sb, d, &p.opts)
+		}
+		sb.WriteString(patchClose)
+		if err := write(sb.String()); err != nil {
+			return err
+		}
+	}
+
+	w.Header().Set("X-Surface-Count", strconv.Itoa(len(surfaces)))
+	return nil
+}
+
+// RequestHeader is the request header the SURF runtime sends, with the
+// value "true", on every request it makes
+const RequestHeader = "X-Surf-Request"
+
+// IsSurfRequest reports whether r was made by the SURF runtime
+func IsSurfRequest(r *http.Request) bool {
+	return r.Header.Get(RequestHeader) == "true"
+}
+
+// WriteNegotiated writes the patch for SURF requests and the full page
+// rendered by RenderPage(layout) for everything else, such as crawlers and
+// clients without the runtime, so one endpoint can serve both. The response
+// varies on RequestHeader.
+func (p *Patch) WriteNegotiated(w http.ResponseWriter, r *http.Request, layout string) error {
+	w.Header().Add("Vary", RequestHeader)
+	if IsSurfRequest(r) {
+		return p.WriteResponse(w)
+	}
+
+	start := writeStart()
+	page, err := p.RenderPage(layout)
+	if err != nil {
+		return err
+	}
+	p.writeHeader(w)
+	n, err := w.Write([]byte(page))
+	notifyWrite(start, w, p.statusCode(), n)
+	return err
+}
+
+// OnWrite, when set, is called after each response-writing helper has
+// written its body, with what was sent. Set it during initialization; it
+// is not guarded for concurrent use and is called on the writing goroutine.
+var OnWrite func(info WriteInfo)
+
+// WriteInfo describes a response written by the response helpers
+type WriteInfo struct {
+	// Bytes is the number of body bytes written
+	Bytes  int64
+	Status int
+	// Duration covers rendering and writing
+	Duration time.Duration
+	// Compressed reports whether a Content-Encoding header was set on the
+	// response, e.g. by compression middleware
+	Compressed bool
+}
+
+// writeStart returns the start time for notifyWrite, skipping the clock
+// read when OnWrite is nil
+func writeStart() time.Time {
+	if OnWrite == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// notifyWrite calls OnWrite, if set, for a response to w
+func notifyWrite(start time.Time, w http.ResponseWriter, status, n int) {
+	if OnWrite == nil {
+		return
+	}
+	OnWrite(WriteInfo{
+		Bytes:      int64(n),
+		Status:     status,
+		Duration:   time.Since(start),
+		Compressed: w.Header().Get("Content-Encoding") != "",
+	})
+}
@@ -0,0 +1,187 @@
+package surf
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWriteChunkedWithTrailer(t *testing.T) {
+	p := NewPatch().AddSurface("#a", "1").AddSurface("#b", "2")
+
+	rec := httptest.NewRecorder()
+	if err := p.WriteChunkedWithTrailer(rec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res := rec.Result()
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != p.Render() {
+		t.Errorf("streamed body differs from Render:\n%s", body)
+	}
+	if got := res.Trailer.Get("X-Surface-Count"); got != "2" {
+		t.Errorf("expected X-Surface-Count trailer 2, got %q", got)
+	}
+}
+
+func TestWriteChunkedWithTrailerFallback(t *testing.T) {
+	p := NewPatch().AddSurface("#a", "1")
+
+	rec := httptest.NewRecorder()
+	// Hide the recorder's Flush method
+	w := struct{ http.ResponseWriter }{rec}
+	if err := p.WriteChunkedWithTrailer(w); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res := rec.Result()
+	if res.Header.Get("Trailer") != "" || len(res.Trailer) != 0 {
+		t.Errorf("fallback should not announce trailers")
+	}
+	if rec.Body.String() != p.Render() {
+		t.Errorf("unexpected body: %s", rec.Body.String())
+	}
+}
+
+func TestWriteChunkedWithTrailerChecks(t *testing.T) {
+	p := NewPatch().AddSurface("#a", "1").RemoveTarget("#a")
+
+	rec := httptest.NewRecorder()
+	if err := p.WriteChunkedWithTrailer(rec); !errors.Is(err, ErrModeConflict) {
+		t.Fatalf("expected ErrModeConflict, got %v", err)
+	}
+	if rec.Body.Len() != 0 || len(rec.Header()) != 0 {
+		t.Errorf("nothing should be written on error: %q", rec.Body.String())
+	}
+}
+
+func TestWithRetryAfter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := NewPatch().
+		AddSurface("#toast", "Slow down").
+		WithStatus(http.StatusTooManyRequests).
+		WithRetryAfter(1500 * time.Millisecond).
+		WriteResponse(rec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("expected Retry-After 2, got %q", got)
+	}
+}
+
+func TestWithRetryAfterOtherStatus(t *testing.T) {
+	rec := httptest.NewRecorder()
+	_ = NewPatch().WithStatus(http.StatusServiceUnavailable).WithRetryAfter(30 * time.Second).WriteResponse(rec)
+
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("expected Retry-After 30 with 503, got %q", got)
+	}
+}
+
+func TestWithRetryAfterUnset(t *testing.T) {
+	rec := httptest.NewRecorder()
+	_ = NewPatch().WithStatus(http.StatusTooManyRequests).WriteResponse(rec)
+
+	if got := rec.Header().Get("Retry-After"); got != "" {
+		t.Errorf("Retry-After should only be sent when set, got %q", got)
+	}
+}
+
+const negotiatedLayout = `<html><body><main>{{surface "#main"}}</main></body></html>`
+
+func TestWriteNegotiatedSurfRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(RequestHeader, "true")
+	rec := httptest.NewRecorder()
+
+	if err := NewPatch().AddSurface("#main", "hi").WriteNegotiated(rec, r, negotiatedLayout); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "<d-patch>\n  <surface target=\"#main\">hi</surface>\n</d-patch>"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if vary := rec.Header().Get("Vary"); vary != RequestHeader {
+		t.Errorf("unexpected Vary %q", vary)
+	}
+}
+
+func TestWriteNegotiatedBrowserRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+
+	if err := NewPatch().AddSurface("#main", "hi").WriteNegotiated(rec, r, negotiatedLayout); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := rec.Body.String(), "<html><body><main>hi</main></body></html>"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+}
+
+func TestOnWrite(t *testing.T) {
+	var infos []WriteInfo
+	OnWrite = func(info WriteInfo) { infos = append(infos, info) }
+	defer func() { OnWrite = nil }()
+
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Encoding", "gzip")
+	p := NewPatch().AddSurface("#a", "1").WithStatus(http.StatusCreated)
+	if err := p.WriteResponse(rec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(infos) != 1 {
+		t.Fatalf("expected one call, got %d", len(infos))
+	}
+	info := infos[0]
+	if info.Bytes != int64(rec.Body.Len()) || info.Status != http.StatusCreated || !info.Compressed {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if info.Duration <= 0 {
+		t.Errorf("expected a positive duration, got %s", info.Duration)
+	}
+}
+
+func TestOnWriteNil(t *testing.T) {
+	if err := NewPatch().AddSurface("#a", "1").WriteResponse(httptest.NewRecorder()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestWriteOrNoContentEmpty(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := NewPatch().WithStatus(http.StatusAccepted).WriteOrNoContent(rec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", rec.Code)
+	}
+	if rec.Body.Len() != 0 || rec.Header().Get("Content-Type") != "" {
+		t.Errorf("204 should have no body or Content-Type, got %q %q", rec.Body.String(), rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestWriteOrNoContentNonEmpty(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := NewPatch().AddSurface("#a", "1").WriteOrNoContent(rec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rec.Code)
+	}
+	if want := "<d-patch>\n  <surface target=\"#a\">1</surface>\n</d-patch>"; rec.Body.String() != want {
+		t.Errorf("got %q, want %q", rec.Body.String(), want)
+	}
+}
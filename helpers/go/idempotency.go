@@ -0,0 +1,55 @@
+package surf
+
+import "net/http"
+
+// IdempotencyStore caches written responses by idempotency key.
+// Implementations must be safe for concurrent use.
+type IdempotencyStore interface {
+	Get(key string) (StoredResponse, bool)
+	Set(key string, res StoredResponse)
+}
+
+// StoredResponse is a response cached by WriteIdempotent
+type StoredResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// WriteIdempotent writes the patch like WriteResponse, caching the response
+// in store under the request's Idempotency-Key header. A repeated key
+// replays the stored response instead of the patch. Requests without the
+// header are written normally and not cached.
+func (p *Patch) WriteIdempotent(w http.ResponseWriter, r *http.Request, store IdempotencyStore) error {
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		return p.WriteResponse(w)
+	}
+
+	start := writeStart()
+	if res, ok := store.Get(key); ok {
+		for name, values := range res.Header {
+			w.Header()[name] = append([]string(nil), values...)
+		}
+		w.WriteHeader(res.Status)
+		n, err := w.Write(res.Body)
+		notifyWrite(start, w, res.Status, n)
+		return err
+	}
+
+	html, err := p.RenderSafe()
+	if err != nil {
+		return err
+	}
+
+	p.writeHeader(w)
+	store.Set(key, StoredResponse{
+		Status: p.statusCode(),
+		Header: w.Header().Clone(),
+		Body:   []byte(html),
+	})
+
+	n, err := w.Write([]byte(html))
+	notifyWrite(start, w, p.statusCode(), n)
+	return err
+}
@@ -0,0 +1,77 @@
+package surf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type memoryStore struct {
+	mu        sync.Mutex
+	responses map[string]StoredResponse
+}
+
+func (s *memoryStore) Get(key string) (StoredResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res, ok := s.responses[key]
+	return res, ok
+}
+
+func (s *memoryStore) Set(key string, res StoredResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[key] = res
+}
+
+func TestWriteIdempotent(t *testing.T) {
+	store := &memoryStore{responses: make(map[string]StoredResponse)}
+	request := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/orders", nil)
+		r.Header.Set("Idempotency-Key", "order-42")
+		return r
+	}
+
+	// Miss: the patch is rendered, stored and written
+	first := httptest.NewRecorder()
+	err := NewPatch().AddSurface("#order", "Order #1 placed").WithStatus(http.StatusCreated).
+		WriteIdempotent(first, request(), store)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d", first.Code)
+	}
+	if _, ok := store.Get("order-42"); !ok {
+		t.Fatalf("response was not stored")
+	}
+
+	// Hit: the stored response is replayed rather than the new patch
+	second := httptest.NewRecorder()
+	err = NewPatch().AddSurface("#order", "Order #2 placed").
+		WriteIdempotent(second, request(), store)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Errorf("expected replay of first response, got %d %s", second.Code, second.Body.String())
+	}
+	if second.Header().Get("Content-Type") != ContentType {
+		t.Errorf("stored headers were not replayed")
+	}
+}
+
+func TestWriteIdempotentWithoutKey(t *testing.T) {
+	store := &memoryStore{responses: make(map[string]StoredResponse)}
+
+	rec := httptest.NewRecorder()
+	_ = NewPatch().AddSurface("#a", "1").WriteIdempotent(rec, httptest.NewRequest(http.MethodPost, "/", nil), store)
+
+	if len(store.responses) != 0 {
+		t.Errorf("requests without a key should not be cached")
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rec.Code)
+	}
+}
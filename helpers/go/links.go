@@ -0,0 +1,89 @@
+package surf
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// RewriteLinks resolves relative href and src attributes in every surface's
+// content against base. Absolute URLs, protocol-relative URLs and fragment
+// links ("#...") are left alone. An invalid base records an error.
+func (p *Patch) RewriteLinks(base string) *Patch {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return p.fail(fmt.Errorf("surf: rewrite links: %w", err))
+	}
+
+	p = p.mutable()
+	for i, s := range p.surfaces {
+		if gen := s.gen; gen != nil {
+			p.surfaces[i].gen = func(ctx context.Context) (string, error) {
+				content, err := gen(ctx)
+				if err != nil {
+					return "", err
+				}
+				return rewriteLinks(content, baseURL), nil
+			}
+			continue
+		}
+		p.surfaces[i].Content = rewriteLinks(s.Content, baseURL)
+	}
+	return p
+}
+
+// rewriteLinks returns content with relative href/src values resolved
+// against base. Tags without such links are copied byte for byte.
+func rewriteLinks(content string, base *url.URL) string {
+	var sb strings.Builder
+	z := html.NewTokenizer(strings.NewReader(content))
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return sb.String()
+		}
+
+		raw := z.Raw()
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			sb.Write(raw)
+			continue
+		}
+
+		token := z.Token()
+		changed := false
+		for i, a := range token.Attr {
+			if a.Namespace != "" || (a.Key != "href" && a.Key != "src") {
+				continue
+			}
+			if resolved, ok := resolveLink(a.Val, base); ok {
+				token.Attr[i].Val = resolved
+				changed = true
+			}
+		}
+
+		if changed {
+			sb.WriteString(token.String())
+		} else {
+			sb.Write(raw)
+		}
+	}
+}
+
+// resolveLink resolves a relative reference against base, reporting false
+// for links that must be left alone
+func resolveLink(link string, base *url.URL) (string, bool) {
+	link = strings.TrimSpace(link)
+	if link == "" || strings.HasPrefix(link, "#") {
+		return "", false
+	}
+
+	ref, err := url.Parse(link)
+	if err != nil || ref.IsAbs() || ref.Host != "" {
+		return "", false
+	}
+	return base.ResolveReference(ref).String(), true
+}
@@ -0,0 +1,42 @@
+package surf
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRewriteLinks(t *testing.T) {
+	p := NewPatch().
+		AddSurface("#nav", `<a href="../about">About</a> <a class="x" href="/docs?q=1">Docs</a> <img src="img/logo.png"/>`).
+		AddSurface("#ext", `<a href="https://example.org/x">Ext</a><a href="//cdn.example/y">CDN</a><a href="mailto:hi@example.org">Mail</a>`).
+		AddSurface("#toc", `<a href="#intro">Intro</a>`).
+		RewriteLinks("https://app.example/blog/posts/")
+
+	assertSurfaces(t, p.Surfaces(), []Surface{
+		{Target: "#nav", Content: `<a href="https://app.example/blog/about">About</a> <a class="x" href="https://app.example/docs?q=1">Docs</a> <img src="https://app.example/blog/posts/img/logo.png"/>`},
+		{Target: "#ext", Content: `<a href="https://example.org/x">Ext</a><a href="//cdn.example/y">CDN</a><a href="mailto:hi@example.org">Mail</a>`},
+		{Target: "#toc", Content: `<a href="#intro">Intro</a>`},
+	})
+}
+
+func TestRewriteLinksGenerator(t *testing.T) {
+	p := NewPatch().
+		AddSurfaceFunc("#lazy", func(ctx context.Context) (string, error) {
+			return `<a href="next">Next</a>`, nil
+		}).
+		RewriteLinks("https://app.example/list/")
+
+	surfaces, err := p.Resolve(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if surfaces[0].Content != `<a href="https://app.example/list/next">Next</a>` {
+		t.Errorf("generator output not rewritten: %s", surfaces[0].Content)
+	}
+}
+
+func TestRewriteLinksInvalidBase(t *testing.T) {
+	if _, err := NewPatch().RewriteLinks("http://[::1").RenderSafe(); err == nil {
+		t.Error("expected error for invalid base")
+	}
+}
@@ -0,0 +1,61 @@
+package surf
+
+import (
+	"net/http"
+	"sort"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLanguage is the variants key used by AddSurfaceLocalized when the
+// request has no Accept-Language header or no variant matches it
+var DefaultLanguage = "en"
+
+// AddSurfaceLocalized adds a surface with the variant that best matches the
+// request's Accept-Language header. Variants are keyed by BCP 47 tag
+// (e.g. "en", "pt-BR"); the DefaultLanguage variant is used as fallback.
+func (p *Patch) AddSurfaceLocalized(target string, variants map[string]string, r *http.Request) *Patch {
+	return p.AddSurface(target, variants[matchLanguage(variants, r.Header.Get("Accept-Language"))])
+}
+
+// matchLanguage returns the variants key that best matches header
+func matchLanguage(variants map[string]string, header string) string {
+	if header == "" {
+		return DefaultLanguage
+	}
+
+	keys := make([]string, 0, len(variants))
+	for key := range variants {
+		if key != DefaultLanguage {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	// The matcher falls back to the first supported tag; the default is
+	// only a candidate when there is a variant for it
+	if _, ok := variants[DefaultLanguage]; ok {
+		keys = append([]string{DefaultLanguage}, keys...)
+	}
+
+	supported := make([]language.Tag, 0, len(keys))
+	supportedKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		tag, err := language.Parse(key)
+		if err != nil {
+			continue
+		}
+		supported = append(supported, tag)
+		supportedKeys = append(supportedKeys, key)
+	}
+
+	desired, _, err := language.ParseAcceptLanguage(header)
+	if err != nil || len(supported) == 0 {
+		return DefaultLanguage
+	}
+
+	_, index, confidence := language.NewMatcher(supported).Match(desired...)
+	if confidence == language.No {
+		return DefaultLanguage
+	}
+	return supportedKeys[index]
+}
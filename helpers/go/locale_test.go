@@ -0,0 +1,51 @@
+package surf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var greetings = map[string]string{
+	"en": "Hello",
+	"de": "Hallo",
+	"tr": "Merhaba",
+}
+
+func localizedRequest(acceptLanguage string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptLanguage != "" {
+		r.Header.Set("Accept-Language", acceptLanguage)
+	}
+	return r
+}
+
+func TestAddSurfaceLocalizedExactMatch(t *testing.T) {
+	html := NewPatch().AddSurfaceLocalized("#greeting", greetings, localizedRequest("tr-TR, de;q=0.8")).Render()
+	if !strings.Contains(html, ">Merhaba<") {
+		t.Errorf("expected Turkish variant: %s", html)
+	}
+}
+
+func TestAddSurfaceLocalizedFallback(t *testing.T) {
+	html := NewPatch().AddSurfaceLocalized("#greeting", greetings, localizedRequest("ja")).Render()
+	if !strings.Contains(html, ">Hello<") {
+		t.Errorf("expected default variant: %s", html)
+	}
+}
+
+func TestAddSurfaceLocalizedMissingHeader(t *testing.T) {
+	html := NewPatch().AddSurfaceLocalized("#greeting", greetings, localizedRequest("")).Render()
+	if !strings.Contains(html, ">Hello<") {
+		t.Errorf("expected default variant: %s", html)
+	}
+}
+
+func TestAddSurfaceLocalizedMissingDefault(t *testing.T) {
+	variants := map[string]string{"de": "Hallo", "fr": "Bonjour"}
+	html := NewPatch().AddSurfaceLocalized("#greeting", variants, localizedRequest("en-US, de;q=0.9")).Render()
+	if !strings.Contains(html, ">Hallo<") {
+		t.Errorf("expected German variant: %s", html)
+	}
+}
@@ -0,0 +1,37 @@
+package surf
+
+import (
+	"context"
+	"net/http"
+)
+
+// LogFields returns the request and patch in a flat shape for structured
+// loggers: method, path, surface count, targets and rendered size in bytes.
+// Slot surfaces are listed in targets as "slot:<name>".
+// Surface content is only included, as "contents", under WithLogContent.
+// The patch is resolved once and every field describes the surfaces as
+// rendered, with aliases resolved and failed or omitted surfaces left out.
+func (p *Patch) LogFields(r *http.Request) map[string]any {
+	html, surfaces, _ := HTMLRenderer{}.render(context.Background(), p)
+
+	targets := make([]string, len(surfaces))
+	for i, s := range surfaces {
+		targets[i] = surfaceKey(s)
+	}
+
+	fields := map[string]any{
+		"method":   r.Method,
+		"path":     r.URL.Path,
+		"surfaces": len(surfaces),
+		"targets":  targets,
+		"bytes":    len(html),
+	}
+	if p.opts.logContent {
+		contents := make([]string, len(surfaces))
+		for i, s := range surfaces {
+			contents[i] = s.Content
+		}
+		fields["contents"] = contents
+	}
+	return fields
+}
@@ -0,0 +1,78 @@
+package surf
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestLogFields(t *testing.T) {
+	r := httptest.NewRequest("POST", "/cart/items?id=7", nil)
+	p := NewPatch().
+		AddSurface("#cart", "jane@example.com").
+		RemoveTarget("#empty-cart").
+		AddSlot("header", "h")
+
+	fields := p.LogFields(r)
+	want := map[string]any{
+		"method":   "POST",
+		"path":     "/cart/items",
+		"surfaces": 3,
+		"targets":  []string{"#cart", "#empty-cart", "slot:header"},
+		"bytes":    p.ContentLength(),
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("got %#v, want %#v", fields, want)
+	}
+}
+
+func TestLogFieldsWithContent(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	fields := NewPatch(WithLogContent()).AddSurface("#cart", "3 items").LogFields(r)
+
+	if got := fields["contents"]; !reflect.DeepEqual(got, []string{"3 items"}) {
+		t.Errorf("unexpected contents %#v", got)
+	}
+}
+
+func TestLogFieldsResolvesOnce(t *testing.T) {
+	calls := 0
+	p := NewPatch(WithLogContent()).AddSurfaceFunc("#cart", func(context.Context) (string, error) {
+		calls++
+		return "3 items", nil
+	})
+
+	fields := p.LogFields(httptest.NewRequest("GET", "/", nil))
+	if calls != 1 {
+		t.Errorf("generator ran %d times, want 1", calls)
+	}
+	if got := fields["contents"]; !reflect.DeepEqual(got, []string{"3 items"}) {
+		t.Errorf("unexpected contents %#v", got)
+	}
+	if got := fields["bytes"]; got != p.ContentLength() {
+		t.Errorf("bytes = %v, want %d", got, p.ContentLength())
+	}
+}
+
+func TestLogFieldsMatchRenderedSurfaces(t *testing.T) {
+	RegisterTarget("@cart", "#cart")
+	t.Cleanup(func() { unregisterTarget("@cart") })
+
+	p := NewPatch(WithLogContent(), WithOmitEmpty()).
+		AddSurface("#a", "").
+		AddSurface("@cart", "B").
+		AddSurfaceFunc("#c", func(context.Context) (string, error) { return "", errors.New("boom") })
+
+	fields := p.LogFields(httptest.NewRequest("GET", "/", nil))
+	if got := fields["targets"]; !reflect.DeepEqual(got, []string{"#cart"}) {
+		t.Errorf("unexpected targets %#v", got)
+	}
+	if got := fields["contents"]; !reflect.DeepEqual(got, []string{"B"}) {
+		t.Errorf("unexpected contents %#v", got)
+	}
+	if got := fields["surfaces"]; got != 1 {
+		t.Errorf("surfaces = %v, want 1", got)
+	}
+}
@@ -0,0 +1,46 @@
+package surf
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// NDJSONContentType is the Content-Type to send with WriteNDJSON output
+const NDJSONContentType = "application/x-ndjson"
+
+// WriteNDJSON writes each surface to w as a JSON object on its own line,
+// in the fields of JSONRenderer. Surfaces are resolved one at a time and
+// w is flushed after each line when it is an http.Flusher, so the client
+// sees slow generators' output progressively. Writing stops at the first
+// error; lines already written stay written.
+func (p *Patch) WriteNDJSON(w io.Writer) error {
+	if err := p.Check(); err != nil {
+		return err
+	}
+	flusher, _ := w.(http.Flusher)
+
+	for _, s := range p.surfaces {
+		single := p.derive()
+		single.surfaces = append(single.surfaces, s)
+
+		resolved, err := single.Resolve(context.Background())
+		if err != nil {
+			return err
+		}
+		for _, r := range resolved {
+			line, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	return nil
+}
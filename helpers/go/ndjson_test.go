@@ -0,0 +1,57 @@
+package surf
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteNDJSON(t *testing.T) {
+	p := NewPatch().
+		AddSurface("#a", "<b>1</b>").
+		AppendSurface("#list", "<li>2</li>").
+		AddSurfaceFunc("#lazy", func(ctx context.Context) (string, error) { return "3", nil })
+
+	rec := httptest.NewRecorder()
+	if err := p.WriteNDJSON(rec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !rec.Flushed {
+		t.Error("expected the writer to be flushed")
+	}
+
+	var got []Surface
+	scanner := bufio.NewScanner(strings.NewReader(rec.Body.String()))
+	for scanner.Scan() {
+		var s Surface
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+			t.Fatalf("line %q is not valid JSON: %v", scanner.Text(), err)
+		}
+		got = append(got, s)
+	}
+	assertSurfaces(t, got, []Surface{
+		{Target: "#a", Content: "<b>1</b>"},
+		{Target: "#list", Content: "<li>2</li>", Mode: ModeAppend},
+		{Target: "#lazy", Content: "3"},
+	})
+}
+
+func TestWriteNDJSONStopsAtError(t *testing.T) {
+	boom := errors.New("boom")
+	p := NewPatch().
+		AddSurface("#a", "1").
+		AddSurfaceFunc("#b", func(ctx context.Context) (string, error) { return "", boom }).
+		AddSurface("#c", "3")
+
+	var sb strings.Builder
+	if err := p.WriteNDJSON(&sb); !errors.Is(err, boom) {
+		t.Fatalf("expected generator error, got %v", err)
+	}
+	if want := `{"target":"#a","content":"1"}` + "\n"; sb.String() != want {
+		t.Errorf("got %q, want %q", sb.String(), want)
+	}
+}
@@ -0,0 +1,44 @@
+package surf
+
+import "context"
+
+// Operation is a structured form of a surface for consumers that apply
+// updates without parsing HTML, such as a virtual DOM
+type Operation struct {
+	// Op is the surface mode; the default mode is reported as ModeReplace
+	Op     Mode   `json:"op"`
+	Target string `json:"target"`
+	// Slot is set, instead of Target, for surfaces added with AddSlot
+	Slot string `json:"slot,omitempty"`
+	// Payload is the HTML string for content operations, an AttrPayload for
+	// ModeAttr and nil for operations without content such as ModeRemove
+	Payload any `json:"payload,omitempty"`
+}
+
+// AttrPayload is the Payload of a ModeAttr operation
+type AttrPayload struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Operations returns the resolved surfaces as an operation list, in order.
+// Errors are ignored as in Render.
+func (p *Patch) Operations() []Operation {
+	surfaces, _ := p.Resolve(context.Background())
+
+	ops := make([]Operation, len(surfaces))
+	for i, s := range surfaces {
+		op := Operation{Op: s.Mode, Target: s.Target, Slot: s.Slot}
+		switch {
+		case s.isReplace():
+			op.Op = ModeReplace
+			op.Payload = s.Content
+		case s.Mode == ModeAttr:
+			op.Payload = AttrPayload{Name: s.Attr, Value: s.Value}
+		case s.carriesContent():
+			op.Payload = s.Content
+		}
+		ops[i] = op
+	}
+	return ops
+}
@@ -0,0 +1,29 @@
+package surf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOperations(t *testing.T) {
+	ops := NewPatch().
+		AddSurface("#main", "<h1>Hi</h1>").
+		AppendSurface("#list", "<li>1</li>").
+		PrependSurface("#list", "<li>0</li>").
+		RemoveTarget("#banner").
+		SetAttr("#btn", "disabled", "true").
+		AddSlot("header", "h").
+		Operations()
+
+	want := []Operation{
+		{Op: ModeReplace, Target: "#main", Payload: "<h1>Hi</h1>"},
+		{Op: ModeAppend, Target: "#list", Payload: "<li>1</li>"},
+		{Op: ModePrepend, Target: "#list", Payload: "<li>0</li>"},
+		{Op: ModeRemove, Target: "#banner"},
+		{Op: ModeAttr, Target: "#btn", Payload: AttrPayload{Name: "disabled", Value: "true"}},
+		{Op: ModeReplace, Slot: "header", Payload: "h"},
+	}
+	if !reflect.DeepEqual(ops, want) {
+		t.Errorf("unexpected operations:\n%+v\n%+v", ops, want)
+	}
+}
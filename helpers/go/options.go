@@ -0,0 +1,178 @@
+package surf
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrScriptContent is returned by RenderSafe under WithNoScripts when a
+// surface contains a <script> tag
+var ErrScriptContent = errors.New("surf: script in surface content")
+
+// ErrSurfaceTooLarge is returned by RenderSafe when a surface exceeds the
+// WithMaxSurfaceBytes limit in OverflowError mode
+var ErrSurfaceTooLarge = errors.New("surf: surface content too large")
+
+// Option configures how a patch is rendered
+type Option func(*options)
+
+type options struct {
+	checksums  bool
+	omitEmpty  bool
+	noScripts  bool
+	logContent bool
+
+	removeMissing bool
+	noAriaInvalid bool
+
+	hydrationPrefix string
+	attrQuote       byte
+	timestamp       bool
+
+	// lineEnding replaces "\n" when customLineEnding is set, so that an
+	// empty line ending can be told apart from the default
+	lineEnding       string
+	customLineEnding bool
+
+	maxSurfaceBytes int
+	overflow        Overflow
+}
+
+// WithChecksums adds a checksum attribute to every surface: the CRC-32
+// (IEEE polynomial) of the rendered content as 8 lowercase hex digits.
+// The client recomputes it to detect content altered in transit.
+func WithChecksums() Option {
+	return func(o *options) {
+		o.checksums = true
+	}
+}
+
+// WithOmitEmpty skips surfaces whose rendered content is empty, so a
+// computed empty result does not blank its target. Surfaces that never
+// carry content, such as remove and attribute surfaces, are always kept.
+func WithOmitEmpty() Option {
+	return func(o *options) {
+		o.omitEmpty = true
+	}
+}
+
+// WithNoScripts makes RenderSafe fail with ErrScriptContent when a surface
+// contains a <script tag, unless it was added with AddScriptSurface.
+// Render, which ignores errors, leaves such surfaces out.
+func WithNoScripts() Option {
+	return func(o *options) {
+		o.noScripts = true
+	}
+}
+
+// Overflow selects what WithMaxSurfaceBytes does with oversized content
+type Overflow int
+
+const (
+	// OverflowError makes RenderSafe fail with ErrSurfaceTooLarge;
+	// Render leaves the surface out
+	OverflowError Overflow = iota
+	// OverflowTruncate cuts the content at the limit, on a UTF-8 rune
+	// boundary, and appends TruncatedIndicator
+	OverflowTruncate
+)
+
+// TruncatedIndicator marks content cut by OverflowTruncate. It is not
+// counted against the limit.
+const TruncatedIndicator = "<!-- surf:truncated -->"
+
+// WithMaxSurfaceBytes caps the content of each surface at n bytes, handling
+// larger content as overflow says. Zero means unlimited. Truncation does not
+// respect HTML structure and may cut through a tag.
+func WithMaxSurfaceBytes(n int, overflow Overflow) Option {
+	return func(o *options) {
+		o.maxSurfaceBytes = n
+		o.overflow = overflow
+	}
+}
+
+// WithLogContent makes LogFields include surface content. It is off by
+// default because content often carries personal data.
+func WithLogContent() Option {
+	return func(o *options) {
+		o.logContent = true
+	}
+}
+
+// WithRemoveMissing makes DiffAgainst add a remove surface for each target
+// of the previous patch that the new patch no longer updates
+func WithRemoveMissing() Option {
+	return func(o *options) {
+		o.removeMissing = true
+	}
+}
+
+// WithNoAriaInvalid makes FormErrors add only the error messages, without
+// marking the fields aria-invalid="true"
+func WithNoAriaInvalid() Option {
+	return func(o *options) {
+		o.noAriaInvalid = true
+	}
+}
+
+// WithLineEnding sets the line break written between the lines of the
+// rendered markup: after the opening tag and after each surface and
+// directive. The default is "\n"; "\r\n" adds a byte per line and ""
+// removes them, changing ContentLength accordingly. Surface content is not
+// altered.
+func WithLineEnding(eol string) Option {
+	return func(o *options) {
+		o.lineEnding = eol
+		o.customLineEnding = true
+	}
+}
+
+// eol returns the configured line ending
+func (o *options) eol() string {
+	if o.customLineEnding {
+		return o.lineEnding
+	}
+	return "\n"
+}
+
+// WithHydrationMarkers wraps the content of each surface between
+// <!--prefix:start--> and <!--prefix:end--> comments so client-side
+// frameworks can find swapped regions and hydrate them. Surfaces without
+// content, such as remove and attribute surfaces, are left unwrapped.
+// The prefix must not contain "--" or ">".
+func WithHydrationMarkers(prefix string) Option {
+	return func(o *options) {
+		o.hydrationPrefix = prefix
+	}
+}
+
+// WithAttrQuote sets the quote character around the attributes of the
+// patch markup to a single quote (') or a double quote ("), the default;
+// other values keep the default. Values are escaped for the chosen quote.
+// Surface content is not altered.
+func WithAttrQuote(quote byte) Option {
+	return func(o *options) {
+		o.attrQuote = quote
+	}
+}
+
+// attr returns ` name="value"` quoted as configured. value must already be
+// escaped for a double-quoted attribute; single quotes are escaped here.
+func (o *options) attr(name, value string) string {
+	if o.attrQuote == '\'' {
+		return " " + name + "='" + strings.ReplaceAll(value, "'", "&#039;") + "'"
+	}
+	return " " + name + `="` + value + `"`
+}
+
+// WithTimestamp adds a generated attribute to the <d-patch> element with
+// the render time in UTC, formatted as RFC 3339, to help spot stale patches
+func WithTimestamp() Option {
+	return func(o *options) {
+		o.timestamp = true
+	}
+}
+
+// now is replaced in tests
+var now = time.Now
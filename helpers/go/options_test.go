@@ -0,0 +1,220 @@
+package surf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithChecksums(t *testing.T) {
+	html := NewPatch(WithChecksums()).AddSurface("#main", "hello").Render()
+	// crc32.ChecksumIEEE("hello") == 0x3610a686
+	if !strings.Contains(html, `<surface target="#main" checksum="3610a686">hello</surface>`) {
+		t.Errorf("unexpected render: %s", html)
+	}
+
+	changed := NewPatch(WithChecksums()).AddSurface("#main", "hello!").Render()
+	if strings.Contains(changed, `checksum="3610a686"`) {
+		t.Errorf("checksum should change with content: %s", changed)
+	}
+}
+
+func TestChecksumsOffByDefault(t *testing.T) {
+	if html := NewPatch().AddSurface("#main", "hello").Render(); strings.Contains(html, "checksum") {
+		t.Errorf("checksums should be opt-in: %s", html)
+	}
+}
+
+func TestWithOmitEmpty(t *testing.T) {
+	html := NewPatch(WithOmitEmpty()).
+		AddSurface("#blank", "").
+		AddSurface("#main", "content").
+		RemoveTarget("#banner").
+		SetAttr("#btn", "disabled", "").
+		Render()
+
+	expected := "<d-patch>\n" +
+		"  <surface target=\"#main\">content</surface>\n" +
+		"  <surface target=\"#banner\" mode=\"remove\"></surface>\n" +
+		"  <surface target=\"#btn\" mode=\"attr\" attr=\"disabled\" value=\"\"></surface>\n" +
+		"</d-patch>"
+	if html != expected {
+		t.Errorf("unexpected render:\n%s", html)
+	}
+}
+
+func TestEmptySurfacesRenderedByDefault(t *testing.T) {
+	if html := NewPatch().AddSurface("#blank", "").Render(); !strings.Contains(html, `<surface target="#blank"></surface>`) {
+		t.Errorf("empty surface should be rendered by default: %s", html)
+	}
+}
+
+func TestWithNoScriptsBlocks(t *testing.T) {
+	p := NewPatch(WithNoScripts()).
+		AddSurface("#safe", "<p>ok</p>").
+		AddSurface("#comment", `<p>hi</p><SCRIPT src="//evil"></SCRIPT>`)
+
+	_, err := p.RenderSafe()
+	if !errors.Is(err, ErrScriptContent) || !strings.Contains(err.Error(), `"#comment"`) {
+		t.Fatalf("expected ErrScriptContent naming #comment, got %v", err)
+	}
+	if html := p.Render(); strings.Contains(html, "#comment") || !strings.Contains(html, "#safe") {
+		t.Errorf("Render should drop the blocked surface: %s", html)
+	}
+}
+
+func TestWithNoScriptsAllowsOptIn(t *testing.T) {
+	html, err := NewPatch(WithNoScripts()).
+		AddScriptSurface("#widget", `<script>init()</script>`).
+		RenderSafe()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(html, "<script>init()</script>") {
+		t.Errorf("opted-in script missing: %s", html)
+	}
+}
+
+func TestMaxSurfaceBytesUnderLimit(t *testing.T) {
+	html, err := NewPatch(WithMaxSurfaceBytes(10, OverflowError)).AddSurface("#a", "1234567890").RenderSafe()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(html, ">1234567890</surface>") {
+		t.Errorf("content should be untouched: %s", html)
+	}
+}
+
+func TestMaxSurfaceBytesTruncatesAtRuneBoundary(t *testing.T) {
+	// "ü" is two bytes, so a 4 byte limit would split the second one
+	html, err := NewPatch(WithMaxSurfaceBytes(4, OverflowTruncate)).AddSurface("#a", "aüüb").RenderSafe()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(html, ">aü"+TruncatedIndicator+"</surface>") {
+		t.Errorf("unexpected truncation: %s", html)
+	}
+}
+
+func TestMaxSurfaceBytesError(t *testing.T) {
+	p := NewPatch(WithMaxSurfaceBytes(4, OverflowError)).
+		AddSurface("#ok", "tiny").
+		AddSurface("#big", "too large")
+
+	_, err := p.RenderSafe()
+	if !errors.Is(err, ErrSurfaceTooLarge) || !strings.Contains(err.Error(), `"#big"`) {
+		t.Fatalf("expected ErrSurfaceTooLarge naming #big, got %v", err)
+	}
+	if html := p.Render(); strings.Contains(html, "#big") || !strings.Contains(html, "#ok") {
+		t.Errorf("Render should leave the oversized surface out: %s", html)
+	}
+}
+
+func TestMaxSurfaceBytesZeroIsUnlimited(t *testing.T) {
+	if _, err := NewPatch(WithMaxSurfaceBytes(0, OverflowError)).AddSurface("#a", strings.Repeat("x", 1<<16)).RenderSafe(); err != nil {
+		t.Errorf("zero limit should be unlimited: %v", err)
+	}
+}
+
+func TestWithLineEnding(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"default", nil, "<d-patch>\n  <surface target=\"#a\">1</surface>\n  <state key=\"k\">2</state>\n</d-patch>"},
+		{"lf", []Option{WithLineEnding("\n")}, "<d-patch>\n  <surface target=\"#a\">1</surface>\n  <state key=\"k\">2</state>\n</d-patch>"},
+		{"crlf", []Option{WithLineEnding("\r\n")}, "<d-patch>\r\n  <surface target=\"#a\">1</surface>\r\n  <state key=\"k\">2</state>\r\n</d-patch>"},
+		{"none", []Option{WithLineEnding("")}, "<d-patch>  <surface target=\"#a\">1</surface>  <state key=\"k\">2</state></d-patch>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPatch(tt.opts...).AddSurface("#a", "1").AddState("k", 2)
+			if got := p.Render(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if got := p.ContentLength(); got != len(tt.want) {
+				t.Errorf("ContentLength = %d, want %d", got, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestWithLineEndingKeepsContent(t *testing.T) {
+	html := NewPatch(WithLineEnding("\r\n")).AddSurface("#a", "line1\nline2").Render()
+	if !strings.Contains(html, ">line1\nline2</surface>") {
+		t.Errorf("content should be unchanged: %q", html)
+	}
+}
+
+func TestWithHydrationMarkers(t *testing.T) {
+	p := NewPatch(WithHydrationMarkers("hx")).
+		AddSurface("#a", "<p>1</p>").
+		AppendSurface("#list", "<li>2</li>").
+		AddSurface("#empty", "").
+		RemoveTarget("#gone").
+		SetAttr("#b", "class", "on")
+
+	expected := "<d-patch>\n" +
+		"  <surface target=\"#a\"><!--hx:start--><p>1</p><!--hx:end--></surface>\n" +
+		"  <surface target=\"#list\" mode=\"append\"><!--hx:start--><li>2</li><!--hx:end--></surface>\n" +
+		"  <surface target=\"#empty\"></surface>\n" +
+		"  <surface target=\"#gone\" mode=\"remove\"></surface>\n" +
+		"  <surface target=\"#b\" mode=\"attr\" attr=\"class\" value=\"on\"></surface>\n" +
+		"</d-patch>"
+	if html := p.Render(); html != expected {
+		t.Errorf("unexpected render:\n%s", html)
+	}
+}
+
+func TestWithAttrQuoteSingle(t *testing.T) {
+	p := NewPatch(WithAttrQuote('\'')).
+		WithSequence(2).
+		AddSurface(`[data-name='x']`, "it's").
+		SetAttr("#a", "title", `O'Brien "Bob"`).
+		AddState("k", "v")
+
+	expected := "<d-patch seq='2'>\n" +
+		"  <surface target='[data-name=&#039;x&#039;]'>it's</surface>\n" +
+		"  <surface target='#a' mode='attr' attr='title' value='O&#039;Brien &quot;Bob&quot;'></surface>\n" +
+		"  <state key='k'>\"v\"</state>\n" +
+		"</d-patch>"
+	html := p.Render()
+	if html != expected {
+		t.Errorf("unexpected render:\n%s", html)
+	}
+
+	parsed, err := Parse(html)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertSurfaces(t, parsed.Surfaces(), []Surface{
+		{Target: `[data-name='x']`, Content: "it's"},
+		{Target: "#a", Mode: ModeAttr, Attr: "title", Value: `O'Brien "Bob"`},
+	})
+}
+
+func TestWithAttrQuoteDefault(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithAttrQuote('"')}, {WithAttrQuote('`')}} {
+		html := NewPatch(opts...).AddSurface(`[data-name='x']`, "1").Render()
+		if !strings.Contains(html, `<surface target="[data-name='x']">1</surface>`) {
+			t.Errorf("unexpected render: %s", html)
+		}
+	}
+}
+
+func TestWithTimestamp(t *testing.T) {
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time {
+		return time.Date(2024, 3, 9, 14, 5, 7, 0, time.FixedZone("CET", 3600))
+	}
+
+	html := NewPatch(WithTimestamp()).AddSurface("#a", "1").Render()
+	if !strings.HasPrefix(html, `<d-patch generated="2024-03-09T13:05:07Z">`) {
+		t.Errorf("unexpected render: %s", html)
+	}
+	if strings.Contains(NewPatch().Render(), "generated=") {
+		t.Error("timestamp should be off by default")
+	}
+}
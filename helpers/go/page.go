@@ -0,0 +1,62 @@
+package surf
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+)
+
+// placeholderPattern matches {{surface "<selector>"}} in a layout
+var placeholderPattern = regexp.MustCompile(`\{\{\s*surface\s+("(?:[^"\\]|\\.)*")\s*\}\}`)
+
+// RenderPage renders the patch as a full page by replacing each
+// {{surface "<selector>"}} placeholder in layout with the content the
+// client would have produced for that target. Replace and morph surfaces
+// set the content, append and prepend add to it and remove and clear empty
+// it. Surfaces without a placeholder are ignored, as are attribute and sibling
+// insertions; placeholders without a surface render empty. Selectors in
+// placeholders may be aliases and use Go string escaping for quotes.
+// The patch is validated with Check first.
+func (p *Patch) RenderPage(layout string) (string, error) {
+	if err := p.Check(); err != nil {
+		return "", err
+	}
+	surfaces, err := p.Resolve(context.Background())
+	if err != nil {
+		return "", err
+	}
+
+	regions := make(map[string]string)
+	for _, s := range surfaces {
+		switch {
+		case s.isReplace(), s.Mode == ModeMorph:
+			regions[s.Target] = s.Content
+		case s.Mode == ModeAppend:
+			regions[s.Target] += s.Content
+		case s.Mode == ModePrepend:
+			regions[s.Target] = s.Content + regions[s.Target]
+		case s.Mode == ModeRemove, s.Mode == ModeClear:
+			regions[s.Target] = ""
+		}
+	}
+
+	var renderErr error
+	page := placeholderPattern.ReplaceAllStringFunc(layout, func(placeholder string) string {
+		quoted := placeholderPattern.FindStringSubmatch(placeholder)[1]
+		selector, err := strconv.Unquote(quoted)
+		if err == nil {
+			selector, err = resolveTarget(selector)
+		}
+		if err != nil {
+			if renderErr == nil {
+				renderErr = err
+			}
+			return ""
+		}
+		return regions[selector]
+	})
+	if renderErr != nil {
+		return "", renderErr
+	}
+	return page, nil
+}
@@ -0,0 +1,47 @@
+package surf
+
+import (
+	"errors"
+	"testing"
+)
+
+const testLayout = `<html><body><nav>{{surface "#nav"}}</nav><main>{{ surface "#main" }}</main><aside>{{surface "[data-slot=\"aside\"]"}}</aside></body></html>`
+
+func TestRenderPage(t *testing.T) {
+	page, err := NewPatch().
+		AddSurface("#main", "<h1>Home</h1>").
+		AppendSurface("#main", "<p>Welcome</p>").
+		AddSurface(`[data-slot="aside"]`, "tips").
+		AddSurface("#toast", "ignored: no placeholder").
+		RenderPage(testLayout)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `<html><body><nav></nav><main><h1>Home</h1><p>Welcome</p></main><aside>tips</aside></body></html>`
+	if page != expected {
+		t.Errorf("unexpected page:\n%s", page)
+	}
+}
+
+func TestRenderPageAliasPlaceholder(t *testing.T) {
+	RegisterTarget("@main", "#main")
+	t.Cleanup(func() { unregisterTarget("@main") })
+
+	page, err := NewPatch().AddSurface("#main", "x").RenderPage(`<main>{{surface "@main"}}</main>`)
+	if err != nil || page != "<main>x</main>" {
+		t.Errorf("unexpected result %q, %v", page, err)
+	}
+
+	_, err = NewPatch().RenderPage(`{{surface "@missing"}}`)
+	if !errors.Is(err, ErrUnknownTarget) {
+		t.Errorf("expected ErrUnknownTarget, got %v", err)
+	}
+}
+
+func TestRenderPageChecks(t *testing.T) {
+	_, err := NewPatch().AddSurface("#main", "x").RemoveTarget("#main").RenderPage(testLayout)
+	if !errors.Is(err, ErrModeConflict) {
+		t.Errorf("expected ErrModeConflict, got %v", err)
+	}
+}
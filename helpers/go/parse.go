@@ -0,0 +1,193 @@
+package surf
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// ErrNoPatch is returned by Parse when the input has no <d-patch> element
+var ErrNoPatch = errors.New("surf: no <d-patch> element found")
+
+// Parse reads <d-patch> markup, as produced by Render, back into a Patch.
+// Elements other than <surface> are kept as directives.
+func Parse(markup string) (*Patch, error) {
+	start := strings.Index(markup, "<d-patch")
+	end := strings.LastIndex(markup, "</d-patch>")
+	if start < 0 || end < start {
+		return nil, ErrNoPatch
+	}
+
+	rootAttrs, body, err := parseAttrs(markup[start+len("<d-patch") : end])
+	if err != nil {
+		return nil, err
+	}
+
+	p := NewPatch()
+	p.seq = parseSeq(rootAttrs)
+	p.requestID = attrValue(rootAttrs, "request-id")
+
+	for {
+		body = strings.TrimLeft(body, " \t\r\n")
+		if body == "" {
+			return p, nil
+		}
+
+		tag := elementName(body)
+		if tag == "" {
+			return nil, fmt.Errorf("surf: unexpected markup in patch: %.20q", body)
+		}
+
+		attrs, rest, err := parseAttrs(body[1+len(tag):])
+		if err != nil {
+			return nil, err
+		}
+
+		if voidDirectives[tag] {
+			p.directives = append(p.directives, directive{
+				tag:   tag,
+				key:   directiveKey(tag, attrs),
+				attrs: attrs,
+			})
+			body = rest
+			continue
+		}
+
+		content, rest, err := splitContent(rest, tag)
+		if err != nil {
+			return nil, err
+		}
+		body = rest
+
+		if tag != "surface" {
+			p.directives = append(p.directives, directive{
+				tag:     tag,
+				key:     directiveKey(tag, attrs),
+				attrs:   attrs,
+				content: content,
+			})
+			continue
+		}
+
+		target := attrValue(attrs, "target")
+		if inner := attrValue(attrs, "shadow"); inner != "" {
+			target = ByShadow(target, inner)
+		}
+		p.surfaces = append(p.surfaces, Surface{
+			Target:      target,
+			Content:     content,
+			Mode:        Mode(attrValue(attrs, "mode")),
+			Attr:        attrValue(attrs, "attr"),
+			Value:       attrValue(attrs, "value"),
+			Seq:         parseSeq(attrs),
+			Slot:        attrValue(attrs, "slot"),
+			contentType: attrValue(attrs, "content-type"),
+		})
+	}
+}
+
+// elementName returns the tag name of the element opening s, or ""
+func elementName(s string) string {
+	if !strings.HasPrefix(s, "<") {
+		return ""
+	}
+	end := strings.IndexAny(s, " \t\r\n>")
+	if end <= 1 {
+		return ""
+	}
+	return s[1:end]
+}
+
+// directiveKey reconstructs the replacement key of a parsed directive
+func directiveKey(tag string, attrs []attribute) string {
+	if tag == "meta" {
+		if name := attrValue(attrs, "name"); name != "" {
+			return "name:" + name
+		}
+		return "property:" + attrValue(attrs, "property")
+	}
+	if tag == "ack" {
+		return attrValue(attrs, "id")
+	}
+	return attrValue(attrs, "key")
+}
+
+// parseSeq returns the seq attribute, or zero if it is absent or invalid
+func parseSeq(attrs []attribute) uint64 {
+	seq, _ := strconv.ParseUint(attrValue(attrs, "seq"), 10, 64)
+	return seq
+}
+
+func attrValue(attrs []attribute, name string) string {
+	for _, a := range attrs {
+		if a.name == name {
+			return a.value
+		}
+	}
+	return ""
+}
+
+// parseAttrs reads attributes up to the end of an opening tag and returns
+// them with the remaining input after '>'
+func parseAttrs(s string) ([]attribute, string, error) {
+	var attrs []attribute
+
+	for {
+		s = strings.TrimLeft(s, " \t\r\n")
+		if s == "" {
+			return nil, "", errors.New("surf: unterminated tag")
+		}
+		if s[0] == '>' {
+			return attrs, s[1:], nil
+		}
+
+		eq := strings.IndexAny(s, "= \t\r\n>")
+		if eq < 0 {
+			return nil, "", errors.New("surf: unterminated tag")
+		}
+		name := s[:eq]
+		s = s[eq:]
+
+		if s[0] != '=' {
+			attrs = append(attrs, attribute{name, ""})
+			continue
+		}
+		s = s[1:]
+
+		if s == "" || (s[0] != '"' && s[0] != '\'') {
+			return nil, "", fmt.Errorf("surf: unquoted value for attribute %q", name)
+		}
+		quote := s[0]
+		closing := strings.IndexByte(s[1:], quote)
+		if closing < 0 {
+			return nil, "", fmt.Errorf("surf: unterminated value for attribute %q", name)
+		}
+		attrs = append(attrs, attribute{name, html.UnescapeString(s[1 : closing+1])})
+		s = s[closing+2:]
+	}
+}
+
+// splitContent returns the content up to the matching closing tag and the
+// input following it, allowing nested elements of the same name
+func splitContent(s, tag string) (string, string, error) {
+	opening, closing := "<"+tag, "</"+tag+">"
+
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != '<' {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(s[i:], closing):
+			if depth == 0 {
+				return s[:i], s[i+len(closing):], nil
+			}
+			depth--
+		case strings.HasPrefix(s[i:], opening) && len(s) > i+len(opening) && strings.IndexByte(" \t\r\n>", s[i+len(opening)]) >= 0:
+			depth++
+		}
+	}
+	return "", "", fmt.Errorf("surf: unterminated %s element", tag)
+}
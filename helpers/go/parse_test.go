@@ -0,0 +1,27 @@
+package surf
+
+import "testing"
+
+func TestParseRoundTrip(t *testing.T) {
+	original := NewPatch().
+		AddSurface(`[data-id="a&b"]`, "<div><surface target=\"#inner\">x</surface></div>").
+		PrependSurface("#list", "<li>1</li>")
+
+	p, err := Parse(original.Render())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := p.Render(); got != original.Render() {
+		t.Errorf("round trip mismatch:\n%s\n%s", got, original.Render())
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	p, err := Parse("<d-patch></d-patch>")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.Surfaces()) != 0 {
+		t.Errorf("expected no surfaces")
+	}
+}
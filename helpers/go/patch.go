@@ -1,53 +1,510 @@
 package surf
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"reflect"
 	"strings"
+	"time"
 )
 
+// ErrNoDefaultTarget is recorded by AddContent when no default target is set
+var ErrNoDefaultTarget = errors.New("surf: no default target set")
+
 // Patch represents a SURF patch response
 type Patch struct {
-	surfaces []surfaceUpdate
+	surfaces   []Surface
+	directives []directive
+	status     int
+	// retryAfter is sent as Retry-After when set
+	retryAfter time.Duration
+	err        error
+	opts       options
+	// seq is rendered as the root seq attribute when non-zero
+	seq uint64
+	// requestID is rendered as the root request-id attribute when set
+	requestID string
+	// defaultTarget is used by AddContent
+	defaultTarget string
+	// frozen marks the shared Empty patch
+	frozen bool
 }
 
-type surfaceUpdate struct {
-	Target  string
-	Content string
+// Mode controls how the client applies a surface
+type Mode string
+
+const (
+	// ModeReplace replaces the target's content (default)
+	ModeReplace Mode = "replace"
+	// ModeAppend appends content to the target
+	ModeAppend Mode = "append"
+	// ModePrepend prepends content to the target
+	ModePrepend Mode = "prepend"
+	// ModeBefore inserts content as a sibling immediately before the target
+	ModeBefore Mode = "before"
+	// ModeAfter inserts content as a sibling immediately after the target
+	ModeAfter Mode = "after"
+	// ModeAttr sets the Attr attribute of the target to Value
+	ModeAttr Mode = "attr"
+	// ModeMorph morphs the target's content into the new content,
+	// preserving unchanged nodes
+	ModeMorph Mode = "morph"
+	// ModeRemove removes the target element
+	ModeRemove Mode = "remove"
+	// ModeClear removes the target's child nodes, keeping the target
+	ModeClear Mode = "clear"
+	// ModeLoadingStart puts the target into the client's loading state
+	ModeLoadingStart Mode = "loading-start"
+	// ModeLoadingEnd takes the target out of the client's loading state
+	ModeLoadingEnd Mode = "loading-end"
+)
+
+// Surface is a single update within a patch
+type Surface struct {
+	Target  string `json:"target"`
+	Content string `json:"content"`
+	// Mode is empty for the default replace behaviour
+	Mode Mode `json:"mode,omitempty"`
+	// Attr and Value are set for ModeAttr surfaces
+	Attr  string `json:"attr,omitempty"`
+	Value string `json:"value,omitempty"`
+	// Seq orders the surface for the client; zero means unsequenced
+	Seq uint64 `json:"seq,omitempty"`
+	// Slot routes the content to a named <slot> instead of a Target;
+	// a surface must not set both
+	Slot string `json:"slot,omitempty"`
+	// Tags group surfaces on the server; they are never rendered
+	Tags []string `json:"-"`
+
+	// gen produces Content at render time when set
+	gen func(ctx context.Context) (string, error)
+	// allowScripts exempts the surface from WithNoScripts
+	allowScripts bool
+	// contentType is the MIME type of AddBinary content
+	contentType string
+	// duration is how long an AddSurfaceTimed generator took
+	duration time.Duration
+	timed    bool
 }
 
 // NewPatch creates a new Patch
-func NewPatch() *Patch {
+func NewPatch(opts ...Option) *Patch {
+	p := &Patch{
+		surfaces: make([]Surface, 0),
+	}
+	for _, opt := range opts {
+		opt(&p.opts)
+	}
+	return p
+}
+
+var empty = &Patch{surfaces: make([]Surface, 0), frozen: true}
+
+// Empty returns a shared empty patch for "nothing changed" responses.
+// It is never modified: any method that would change it returns a new
+// patch instead, so always use the returned value when chaining.
+func Empty() *Patch {
+	return empty
+}
+
+// IsEmpty reports whether the patch has no surfaces or directives
+func (p *Patch) IsEmpty() bool {
+	return len(p.surfaces) == 0 && len(p.directives) == 0
+}
+
+// mutable returns p, or a fresh patch if p is the shared Empty patch
+func (p *Patch) mutable() *Patch {
+	if p.frozen {
+		return NewPatch()
+	}
+	return p
+}
+
+// add appends s to the patch
+func (p *Patch) add(s Surface) *Patch {
+	p = p.mutable()
+	p.surfaces = append(p.surfaces, s)
+	return p
+}
+
+// derive returns an empty patch with the same settings as p
+func (p *Patch) derive() *Patch {
 	return &Patch{
-		surfaces: make([]surfaceUpdate, 0),
+		surfaces:      make([]Surface, 0),
+		status:        p.status,
+		retryAfter:    p.retryAfter,
+		err:           p.err,
+		opts:          p.opts,
+		defaultTarget: p.defaultTarget,
+		requestID:     p.requestID,
+	}
+}
+
+// Err returns the first error recorded while building the patch
+func (p *Patch) Err() error {
+	return p.err
+}
+
+// fail records err unless an earlier error was already recorded
+func (p *Patch) fail(err error) *Patch {
+	p = p.mutable()
+	if p.err == nil {
+		p.err = err
 	}
+	return p
+}
+
+// Surfaces returns a copy of the surfaces in the patch, in order.
+// Generator-backed surfaces have empty content; use Resolve to run them.
+func (p *Patch) Surfaces() []Surface {
+	return append([]Surface(nil), p.surfaces...)
 }
 
-// AddSurface adds a surface update to the patch
+// StrictEscaping makes AddSurface treat its content as text and escape it,
+// so raw HTML must go through AddTrusted. Helpers that take caller content
+// for a replace, such as AddSurfaceIf, AddContent, Unshift,
+// AddSurfaceTagged, AddSurfaceSeq, AddSurfaceTimed, AddSurfaceFunc,
+// AddCached and AddAdaptive, escape too; the other insertion methods
+// (AppendSurface, InsertBefore, ...) do not. Helpers that build their own markup, such as
+// RecoverPatch, FormErrors and SurfaceBuilder, escape the values they embed
+// and insert the result as trusted HTML. Enabling it changes the output of
+// existing AddSurface calls that insert HTML, which must move to
+// AddTrusted. Set it during initialization; it is not guarded for
+// concurrent use.
+var StrictEscaping bool
+
+// AddSurface adds a surface update to the patch.
+// The target may be a registered alias (see RegisterTarget).
+// Under StrictEscaping the content is escaped as text.
 func (p *Patch) AddSurface(target, content string) *Patch {
-	p.surfaces = append(p.surfaces, surfaceUpdate{
+	return p.AddTrusted(target, strictText(content))
+}
+
+// AddTrusted adds a surface update whose content is inserted as raw HTML,
+// even under StrictEscaping. Only pass content that is safe to render.
+func (p *Patch) AddTrusted(target, content string) *Patch {
+	return p.add(Surface{
 		Target:  target,
 		Content: content,
 	})
+}
+
+// AddSlot adds a surface delivering content into the <slot> named slotName,
+// for slot-based components, instead of targeting a selector
+func (p *Patch) AddSlot(slotName, content string) *Patch {
+	return p.add(Surface{
+		Slot:    slotName,
+		Content: content,
+	})
+}
+
+// AddScriptSurface adds a surface update whose content may contain
+// <script> tags even when WithNoScripts is enabled
+func (p *Patch) AddScriptSurface(target, content string) *Patch {
+	return p.add(Surface{
+		Target:       target,
+		Content:      content,
+		allowScripts: true,
+	})
+}
+
+// AddSurfaceIf adds the surface only when cond is true
+func (p *Patch) AddSurfaceIf(cond bool, target, content string) *Patch {
+	if !cond {
+		return p
+	}
+	return p.AddSurface(target, content)
+}
+
+// AddSurfaceUnless adds the surface only when cond is false
+func (p *Patch) AddSurfaceUnless(cond bool, target, content string) *Patch {
+	return p.AddSurfaceIf(!cond, target, content)
+}
+
+// WithDefaultTarget sets the target used by AddContent
+func (p *Patch) WithDefaultTarget(target string) *Patch {
+	p = p.mutable()
+	p.defaultTarget = target
 	return p
 }
 
-// Render generates the HTML for the patch
-func (p *Patch) Render() string {
-	if len(p.surfaces) == 0 {
-		return "<d-patch></d-patch>"
+// AddContent adds a surface for the default target.
+// Without a default target it records ErrNoDefaultTarget and adds nothing.
+func (p *Patch) AddContent(content string) *Patch {
+	if p.defaultTarget == "" {
+		return p.fail(ErrNoDefaultTarget)
+	}
+	return p.AddSurface(p.defaultTarget, content)
+}
+
+// Unshift inserts a replace surface at the front of the patch so it is
+// applied before the existing surfaces. Unlike PrependSurface it does not
+// change how the client applies the content.
+func (p *Patch) Unshift(target, content string) *Patch {
+	p = p.mutable()
+	p.surfaces = append([]Surface{{Target: target, Content: strictText(content)}}, p.surfaces...)
+	return p
+}
+
+// AppendSurface adds a surface that appends content to the target
+func (p *Patch) AppendSurface(target, content string) *Patch {
+	return p.add(Surface{
+		Target:  target,
+		Content: content,
+		Mode:    ModeAppend,
+	})
+}
+
+// PrependSurface adds a surface that prepends content to the target
+func (p *Patch) PrependSurface(target, content string) *Patch {
+	return p.add(Surface{
+		Target:  target,
+		Content: content,
+		Mode:    ModePrepend,
+	})
+}
+
+// InsertBefore adds a surface whose content the client inserts immediately
+// before the target element, outside of it
+func (p *Patch) InsertBefore(target, content string) *Patch {
+	return p.add(Surface{
+		Target:  target,
+		Content: content,
+		Mode:    ModeBefore,
+	})
+}
+
+// InsertAfter adds a surface whose content the client inserts immediately
+// after the target element, outside of it
+func (p *Patch) InsertAfter(target, content string) *Patch {
+	return p.add(Surface{
+		Target:  target,
+		Content: content,
+		Mode:    ModeAfter,
+	})
+}
+
+// AppendPage adds the surfaces for one page of an infinite list: itemsHTML
+// is appended to containerTarget and the nextSentinelTarget element, the
+// old "load more" sentinel, is replaced by sentinelHTML, or removed when
+// sentinelHTML is empty. The new sentinel is inserted after the old one
+// before that is removed, so both may match the same selector.
+func (p *Patch) AppendPage(containerTarget, itemsHTML, nextSentinelTarget, sentinelHTML string) *Patch {
+	p = p.AppendSurface(containerTarget, itemsHTML)
+	if sentinelHTML != "" {
+		p = p.InsertAfter(nextSentinelTarget, sentinelHTML)
 	}
+	return p.RemoveTarget(nextSentinelTarget)
+}
+
+// RemoveTarget adds a surface that removes the target element from the page
+func (p *Patch) RemoveTarget(target string) *Patch {
+	return p.add(Surface{
+		Target: target,
+		Mode:   ModeRemove,
+	})
+}
+
+// ClearChildren adds a surface that empties the container target, removing
+// all of its child nodes but not the container itself. Unlike a replace with
+// empty content it carries no content, so WithOmitEmpty keeps it.
+func (p *Patch) ClearChildren(containerTarget string) *Patch {
+	return p.add(Surface{
+		Target: containerTarget,
+		Mode:   ModeClear,
+	})
+}
+
+// ShowLoading adds a surface that puts the target into a loading state.
+// The client marks the target busy (aria-busy="true" and a loading class)
+// and shows its standard indicator until a matching HideLoading arrives,
+// so handlers need not send spinner markup.
+func (p *Patch) ShowLoading(target string) *Patch {
+	return p.add(Surface{
+		Target: target,
+		Mode:   ModeLoadingStart,
+	})
+}
 
-	var sb strings.Builder
-	sb.WriteString("<d-patch>\n")
+// HideLoading adds a surface that ends the target's loading state
+func (p *Patch) HideLoading(target string) *Patch {
+	return p.add(Surface{
+		Target: target,
+		Mode:   ModeLoadingEnd,
+	})
+}
+
+// DuplicateTargets returns the targets that have more than one replace
+// surface, in order of first occurrence. Append and prepend surfaces are
+// not counted.
+func (p *Patch) DuplicateTargets() []string {
+	counts := make(map[string]int)
+	var order []string
+
+	for _, s := range p.surfaces {
+		if !s.isReplace() || s.Slot != "" {
+			continue
+		}
+		if counts[s.Target] == 0 {
+			order = append(order, s.Target)
+		}
+		counts[s.Target]++
+	}
+
+	var dups []string
+	for _, target := range order {
+		if counts[target] > 1 {
+			dups = append(dups, target)
+		}
+	}
+	return dups
+}
+
+// AddSurfaceTagged adds a surface update labelled with tags
+func (p *Patch) AddSurfaceTagged(target, content string, tags ...string) *Patch {
+	return p.add(Surface{
+		Target:  target,
+		Content: strictText(content),
+		Tags:    tags,
+	})
+}
+
+// RemoveTagged drops every surface labelled with tag from the patch
+func (p *Patch) RemoveTagged(tag string) *Patch {
+	p = p.mutable()
+	kept := p.surfaces[:0]
+	for _, s := range p.surfaces {
+		if !s.HasTag(tag) {
+			kept = append(kept, s)
+		}
+	}
+	p.surfaces = kept
+	return p
+}
 
+// BatchAppends merges each run of consecutive append surfaces for the same
+// target into one append with the concatenated content, so the client
+// inserts once. Appends separated by another surface, generator-backed
+// appends and appends with different seq or script exemptions stay apart.
+// Tags of merged surfaces are combined.
+func (p *Patch) BatchAppends() *Patch {
+	p = p.mutable()
+	batched := p.surfaces[:0]
 	for _, s := range p.surfaces {
-		sb.WriteString(fmt.Sprintf("  <surface target=\"%s\">%s</surface>\n", escapeHtml(s.Target), s.Content))
+		if n := len(batched); n > 0 && canBatch(batched[n-1], s) {
+			last := &batched[n-1]
+			last.Content += s.Content
+			last.Tags = append(last.Tags[:len(last.Tags):len(last.Tags)], s.Tags...)
+			continue
+		}
+		batched = append(batched, s)
 	}
+	p.surfaces = batched
+	return p
+}
 
-	sb.WriteString("</d-patch>")
-	return sb.String()
+// canBatch reports whether append surface b can be merged into a
+func canBatch(a, b Surface) bool {
+	return a.Mode == ModeAppend && b.Mode == ModeAppend &&
+		a.Target == b.Target &&
+		a.gen == nil && b.gen == nil &&
+		a.Seq == b.Seq &&
+		a.allowScripts == b.allowScripts
 }
 
+// HasTag reports whether the surface is labelled with tag
+func (s Surface) HasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// AddSurfaceStringer adds a surface whose content is content.String().
+// String is called immediately, so later changes to content are not reflected.
+// A nil Stringer yields empty content.
+func (p *Patch) AddSurfaceStringer(target string, content fmt.Stringer) *Patch {
+	if isNil(content) {
+		return p.AddSurface(target, "")
+	}
+	return p.AddSurface(target, content.String())
+}
+
+// AddSurfaceFunc adds a surface whose content is produced by gen at render time.
+// Surfaces whose generator fails are left out of the rendered patch.
+// Under StrictEscaping the generated content is escaped as text.
+func (p *Patch) AddSurfaceFunc(target string, gen func(ctx context.Context) (string, error)) *Patch {
+	if StrictEscaping {
+		raw := gen
+		gen = func(ctx context.Context) (string, error) {
+			content, err := raw(ctx)
+			return escapeText(content), err
+		}
+	}
+	return p.add(Surface{
+		Target: target,
+		gen:    gen,
+	})
+}
+
+func (s Surface) isReplace() bool {
+	return s.Mode == "" || s.Mode == ModeReplace
+}
+
+// appliesInside reports whether the surface changes the target's own
+// content, as opposed to its siblings or attributes
+func (s Surface) appliesInside() bool {
+	return s.isReplace() || s.Mode == ModeMorph || s.Mode == ModeAppend || s.Mode == ModePrepend
+}
+
+// carriesContent reports whether the surface delivers HTML content, so that
+// empty content is meaningful (it clears or inserts nothing)
+func (s Surface) carriesContent() bool {
+	return s.appliesInside() || s.Mode == ModeBefore || s.Mode == ModeAfter
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// escapeAttr escapes s for use in a quoted HTML attribute value
+func escapeAttr(s string) string {
+	return attrEscaper.Replace(s)
+}
+
+// escapeText escapes s for use as HTML text content
+func escapeText(s string) string {
+	return attrEscaper.Replace(s)
+}
+
+// strictText escapes s as text under StrictEscaping and returns it as is
+// otherwise
+func strictText(s string) string {
+	if StrictEscaping {
+		return escapeText(s)
+	}
+	return s
+}
+
+var attrEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	"\"", "&quot;",
+	"'", "&#039;",
+)
+
 func escapeHtml(s string) string {
 	return strings.ReplaceAll(strings.ReplaceAll(s, "&", "&amp;"), "\"", "&quot;")
 }
@@ -0,0 +1,402 @@
+package surf
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type card struct{ title string }
+
+func (c *card) String() string { return "<div>" + c.title + "</div>" }
+
+func TestAddSurfaceStringer(t *testing.T) {
+	html := NewPatch().AddSurfaceStringer("#card", &card{title: "Hello"}).Render()
+	if !strings.Contains(html, `<surface target="#card"><div>Hello</div></surface>`) {
+		t.Errorf("unexpected render: %s", html)
+	}
+}
+
+func TestAddSurfaceStringerNil(t *testing.T) {
+	var c *card
+	html := NewPatch().
+		AddSurfaceStringer("#a", nil).
+		AddSurfaceStringer("#b", c).
+		Render()
+
+	if !strings.Contains(html, `<surface target="#a"></surface>`) ||
+		!strings.Contains(html, `<surface target="#b"></surface>`) {
+		t.Errorf("nil stringers should render empty: %s", html)
+	}
+}
+
+func TestRenderWithTimeout(t *testing.T) {
+	p := NewPatch().
+		AddSurface("#fast", "ok").
+		AddSurfaceFunc("#slow", func(ctx context.Context) (string, error) {
+			select {
+			case <-time.After(time.Second):
+				return "late", nil
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		})
+
+	html, err := p.RenderWithTimeout(20 * time.Millisecond)
+
+	var timeout *RenderTimeoutError
+	if !errors.As(err, &timeout) {
+		t.Fatalf("expected *RenderTimeoutError, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("timeout error should unwrap to context.DeadlineExceeded")
+	}
+	if len(timeout.Completed) != 1 || timeout.Completed[0] != "#fast" {
+		t.Errorf("unexpected completed targets: %v", timeout.Completed)
+	}
+	if !strings.Contains(html, `<surface target="#fast">ok</surface>`) || strings.Contains(html, "#slow") {
+		t.Errorf("unexpected partial render: %s", html)
+	}
+}
+
+func TestRenderWithTimeoutCompletes(t *testing.T) {
+	p := NewPatch().AddSurfaceFunc("#gen", func(ctx context.Context) (string, error) {
+		return "done", nil
+	})
+
+	html, err := p.RenderWithTimeout(time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(html, `<surface target="#gen">done</surface>`) {
+		t.Errorf("unexpected render: %s", html)
+	}
+}
+
+func TestModeAttributes(t *testing.T) {
+	html := NewPatch().
+		AppendSurface("#list", "<li>a</li>").
+		PrependSurface("#list", "<li>b</li>").
+		Render()
+
+	if !strings.Contains(html, `<surface target="#list" mode="append"><li>a</li></surface>`) ||
+		!strings.Contains(html, `<surface target="#list" mode="prepend"><li>b</li></surface>`) {
+		t.Errorf("unexpected render: %s", html)
+	}
+}
+
+func TestDuplicateTargets(t *testing.T) {
+	p := NewPatch().
+		AddSurface("#main", "a").
+		AddSurface("#toast", "b").
+		AddSurface("#main", "c").
+		AppendSurface("#toast", "d")
+
+	dups := p.DuplicateTargets()
+	if len(dups) != 1 || dups[0] != "#main" {
+		t.Errorf("expected [#main], got %v", dups)
+	}
+
+	clean := NewPatch().AddSurface("#main", "a").AppendSurface("#main", "b").AppendSurface("#main", "c")
+	if dups := clean.DuplicateTargets(); len(dups) != 0 {
+		t.Errorf("expected no duplicates, got %v", dups)
+	}
+}
+
+func TestUnshift(t *testing.T) {
+	html := NewPatch().
+		AddSurface("#child", "child").
+		Unshift("#container", "<div id=\"child\"></div>").
+		Render()
+
+	expected := "<d-patch>\n" +
+		"  <surface target=\"#container\"><div id=\"child\"></div></surface>\n" +
+		"  <surface target=\"#child\">child</surface>\n" +
+		"</d-patch>"
+	if html != expected {
+		t.Errorf("unexpected render:\n%s", html)
+	}
+}
+
+func TestInsertBeforeAfter(t *testing.T) {
+	html := NewPatch().
+		InsertBefore("#item-3", "<li>2.5</li>").
+		InsertAfter("#item-3", "<li>3.5</li>").
+		Render()
+
+	if !strings.Contains(html, `<surface target="#item-3" mode="before"><li>2.5</li></surface>`) {
+		t.Errorf("missing before surface: %s", html)
+	}
+	if !strings.Contains(html, `<surface target="#item-3" mode="after"><li>3.5</li></surface>`) {
+		t.Errorf("missing after surface: %s", html)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	if Empty().Render() != "<d-patch></d-patch>" {
+		t.Errorf("unexpected render: %s", Empty().Render())
+	}
+	if Empty() != Empty() {
+		t.Errorf("Empty should return a shared patch")
+	}
+	if !Empty().IsEmpty() {
+		t.Errorf("Empty should report IsEmpty")
+	}
+}
+
+func TestEmptyIsNotMutated(t *testing.T) {
+	p := Empty().AddSurface("#main", "x").WithStatus(201)
+
+	if p == Empty() {
+		t.Fatalf("mutating Empty should return a new patch")
+	}
+	if len(p.Surfaces()) != 1 || p.statusCode() != 201 {
+		t.Errorf("fresh patch should carry the change")
+	}
+	if !Empty().IsEmpty() || Empty().statusCode() != 200 {
+		t.Errorf("Empty was mutated")
+	}
+}
+
+func TestAddSurfaceIf(t *testing.T) {
+	p := NewPatch().
+		AddSurfaceIf(true, "#shown", "yes").
+		AddSurfaceIf(false, "#hidden", "no").
+		AddSurfaceUnless(false, "#unless-shown", "yes").
+		AddSurfaceUnless(true, "#unless-hidden", "no")
+
+	var targets []string
+	for _, s := range p.Surfaces() {
+		targets = append(targets, s.Target)
+	}
+	if strings.Join(targets, ",") != "#shown,#unless-shown" {
+		t.Errorf("unexpected targets: %v", targets)
+	}
+}
+
+func TestAddContentWithDefaultTarget(t *testing.T) {
+	html, err := NewPatch().WithDefaultTarget("#main").AddContent("<p>one</p>").RenderSafe()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(html, `<surface target="#main"><p>one</p></surface>`) {
+		t.Errorf("unexpected render: %s", html)
+	}
+}
+
+func TestAddContentWithoutDefaultTarget(t *testing.T) {
+	p := NewPatch().AddContent("<p>lost</p>").AddSurface("#other", "x")
+
+	if !errors.Is(p.Err(), ErrNoDefaultTarget) {
+		t.Fatalf("expected ErrNoDefaultTarget, got %v", p.Err())
+	}
+	if _, err := p.RenderSafe(); !errors.Is(err, ErrNoDefaultTarget) {
+		t.Errorf("RenderSafe should report the recorded error, got %v", err)
+	}
+}
+
+// assertSurfaces compares the exported fields of got and want
+func assertSurfaces(t *testing.T, got, want []Surface) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d surfaces, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Target != w.Target || g.Content != w.Content || g.Mode != w.Mode || g.Attr != w.Attr || g.Value != w.Value ||
+			g.Seq != w.Seq || g.Slot != w.Slot || g.contentType != w.contentType {
+			t.Errorf("surface %d: expected %+v, got %+v", i, w, g)
+		}
+	}
+}
+
+func TestTaggedSurfaces(t *testing.T) {
+	p := NewPatch().
+		AddSurfaceTagged("#nav", "nav", "navigation").
+		AddSurfaceTagged("#crumbs", "crumbs", "navigation", "secondary").
+		AddSurface("#main", "main").
+		AddSurfaceTagged("#aside", "aside", "secondary")
+
+	nav := p.RenderWithTags("navigation")
+	expected := "<d-patch>\n" +
+		"  <surface target=\"#nav\">nav</surface>\n" +
+		"  <surface target=\"#crumbs\">crumbs</surface>\n" +
+		"</d-patch>"
+	if nav != expected {
+		t.Errorf("unexpected tagged render:\n%s", nav)
+	}
+	if strings.Contains(p.Render(), "navigation") {
+		t.Errorf("tags must not be rendered")
+	}
+
+	p.RemoveTagged("secondary")
+	assertSurfaces(t, p.Surfaces(), []Surface{
+		{Target: "#nav", Content: "nav"},
+		{Target: "#main", Content: "main"},
+	})
+}
+
+func TestLoadingDirectives(t *testing.T) {
+	html := NewPatch(WithOmitEmpty()).ShowLoading("#results").HideLoading("#spinner").Render()
+
+	expected := "<d-patch>\n" +
+		"  <surface target=\"#results\" mode=\"loading-start\"></surface>\n" +
+		"  <surface target=\"#spinner\" mode=\"loading-end\"></surface>\n" +
+		"</d-patch>"
+	if html != expected {
+		t.Errorf("unexpected render:\n%s", html)
+	}
+}
+
+func TestClearChildren(t *testing.T) {
+	p := NewPatch(WithOmitEmpty()).ClearChildren("#results")
+
+	expected := "<d-patch>\n" +
+		"  <surface target=\"#results\" mode=\"clear\"></surface>\n" +
+		"</d-patch>"
+	if html := p.Render(); html != expected {
+		t.Errorf("unexpected render:\n%s", html)
+	}
+	assertSurfaces(t, p.Surfaces(), []Surface{{Target: "#results", Mode: ModeClear}})
+}
+
+func TestBatchAppends(t *testing.T) {
+	p := NewPatch().
+		AppendSurface("#list", "<li>1</li>").
+		AppendSurface("#list", "<li>2</li>").
+		AppendSurface("#list", "<li>3</li>").
+		AddSurface("#count", "3")
+
+	assertSurfaces(t, p.BatchAppends().Surfaces(), []Surface{
+		{Target: "#list", Content: "<li>1</li><li>2</li><li>3</li>", Mode: ModeAppend},
+		{Target: "#count", Content: "3"},
+	})
+}
+
+func TestBatchAppendsInterleaved(t *testing.T) {
+	p := NewPatch().
+		AppendSurface("#a", "1").
+		AppendSurface("#b", "2").
+		AppendSurface("#a", "3").
+		AppendSurface("#a", "4").
+		AddSurface("#a", "5").
+		AppendSurface("#a", "6")
+
+	assertSurfaces(t, p.BatchAppends().Surfaces(), []Surface{
+		{Target: "#a", Content: "1", Mode: ModeAppend},
+		{Target: "#b", Content: "2", Mode: ModeAppend},
+		{Target: "#a", Content: "34", Mode: ModeAppend},
+		{Target: "#a", Content: "5"},
+		{Target: "#a", Content: "6", Mode: ModeAppend},
+	})
+}
+
+func TestStrictEscaping(t *testing.T) {
+	StrictEscaping = true
+	defer func() { StrictEscaping = false }()
+
+	p := NewPatch().
+		AddSurface("#comment", `<img src=x onerror="alert(1)">`).
+		AddTrusted("#card", `<div class="card">ok</div>`).
+		AddSurfaceTagged("#tagged", "<b>x</b>", "t").
+		AddSurfaceSeq("#seq", "<b>x</b>", 2).
+		AddSurfaceTimed("#timed", func() string { return "<b>x</b>" }).
+		AddSurfaceFunc("#gen", func(context.Context) (string, error) { return "<b>x</b>", nil }).
+		Unshift("#first", "<b>x</b>")
+
+	surfaces, err := p.Resolve(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertSurfaces(t, surfaces, []Surface{
+		{Target: "#first", Content: "&lt;b&gt;x&lt;/b&gt;"},
+		{Target: "#comment", Content: "&lt;img src=x onerror=&quot;alert(1)&quot;&gt;"},
+		{Target: "#card", Content: `<div class="card">ok</div>`},
+		{Target: "#tagged", Content: "&lt;b&gt;x&lt;/b&gt;"},
+		{Target: "#seq", Content: "&lt;b&gt;x&lt;/b&gt;", Seq: 2},
+		{Target: "#timed", Content: "&lt;b&gt;x&lt;/b&gt;"},
+		{Target: "#gen", Content: "&lt;b&gt;x&lt;/b&gt;"},
+	})
+}
+
+func TestStrictEscapingOff(t *testing.T) {
+	p := NewPatch().AddSurface("#a", "<b>raw</b>")
+	assertSurfaces(t, p.Surfaces(), []Surface{{Target: "#a", Content: "<b>raw</b>"}})
+}
+
+func TestAppendPage(t *testing.T) {
+	p := NewPatch().AppendPage("#items", "<li>3</li><li>4</li>", "#more", `<button id="more" data-page="3">More</button>`)
+
+	assertSurfaces(t, p.Surfaces(), []Surface{
+		{Target: "#items", Content: "<li>3</li><li>4</li>", Mode: ModeAppend},
+		{Target: "#more", Content: `<button id="more" data-page="3">More</button>`, Mode: ModeAfter},
+		{Target: "#more", Mode: ModeRemove},
+	})
+	if err := p.Check(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestAppendPageLast(t *testing.T) {
+	p := NewPatch().AppendPage("#items", "<li>9</li>", "#more", "")
+
+	assertSurfaces(t, p.Surfaces(), []Surface{
+		{Target: "#items", Content: "<li>9</li>", Mode: ModeAppend},
+		{Target: "#more", Mode: ModeRemove},
+	})
+}
+
+func TestAddSlot(t *testing.T) {
+	p := NewPatch().
+		AddSlot(`header"`, "<h1>Hi</h1>").
+		AddSlot("footer", "bye").
+		AddSurface("#main", "x")
+
+	expected := "<d-patch>\n" +
+		"  <surface slot=\"header&quot;\"><h1>Hi</h1></surface>\n" +
+		"  <surface slot=\"footer\">bye</surface>\n" +
+		"  <surface target=\"#main\">x</surface>\n" +
+		"</d-patch>"
+	html, err := p.RenderSafe()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if html != expected {
+		t.Errorf("unexpected render:\n%s", html)
+	}
+
+	parsed, err := Parse(html)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.Render() != html {
+		t.Errorf("round trip mismatch:\n%s", parsed.Render())
+	}
+}
+
+func TestSlotAndTargetExclusive(t *testing.T) {
+	p, err := Parse(`<d-patch><surface target="#a" slot="header">x</surface></d-patch>`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := p.Check(); !errors.Is(err, ErrSlotAndTarget) {
+		t.Errorf("expected ErrSlotAndTarget from Check, got %v", err)
+	}
+	if _, err := p.RenderSafe(); !errors.Is(err, ErrSlotAndTarget) {
+		t.Errorf("expected ErrSlotAndTarget from RenderSafe, got %v", err)
+	}
+}
+
+func TestSlotsCoalesceSeparately(t *testing.T) {
+	c := NewCoalescer()
+	c.Add(NewPatch().AddSlot("a", "1").AddSlot("b", "2"))
+	c.Add(NewPatch().AddSlot("a", "3"))
+
+	assertSurfaces(t, c.Flush().Surfaces(), []Surface{
+		{Slot: "b", Content: "2"},
+		{Slot: "a", Content: "3"},
+	})
+}
@@ -0,0 +1,31 @@
+package surf
+
+import (
+	"io"
+	"strings"
+)
+
+// Reader returns an io.Reader over the rendered patch, e.g. for use as a
+// request body. The patch is rendered on the first Read; a render error is
+// returned from Read.
+func (p *Patch) Reader() io.Reader {
+	return &patchReader{patch: p}
+}
+
+type patchReader struct {
+	patch *Patch
+	r     *strings.Reader
+	err   error
+}
+
+func (pr *patchReader) Read(b []byte) (int, error) {
+	if pr.r == nil && pr.err == nil {
+		var html string
+		html, pr.err = pr.patch.RenderSafe()
+		pr.r = strings.NewReader(html)
+	}
+	if pr.err != nil {
+		return 0, pr.err
+	}
+	return pr.r.Read(b)
+}
@@ -0,0 +1,43 @@
+package surf
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReaderSmallChunks(t *testing.T) {
+	p := NewPatch().
+		AddSurface("#main", "<h1>"+strings.Repeat("long ", 20)+"</h1>").
+		AppendSurface("#list", "<li>é</li>")
+
+	var assembled []byte
+	buf := make([]byte, 3)
+	r := p.Reader()
+	for {
+		n, err := r.Read(buf)
+		assembled = append(assembled, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if string(assembled) != p.Render() {
+		t.Errorf("assembled output differs from Render:\n%s", assembled)
+	}
+	if err := iotest.TestReader(p.Reader(), []byte(p.Render())); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReaderRenderError(t *testing.T) {
+	_, err := io.ReadAll(NewPatch().AddSurface("@nowhere", "x").Reader())
+	if !errors.Is(err, ErrUnknownTarget) {
+		t.Errorf("expected ErrUnknownTarget, got %v", err)
+	}
+}
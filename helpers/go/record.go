@@ -0,0 +1,19 @@
+package surf
+
+import (
+	"net/http"
+	"net/http/httptest"
+)
+
+// Record runs h against a response recorder and parses the body back into
+// a Patch. It is meant for handler tests.
+func Record(h http.HandlerFunc, r *http.Request) (*Patch, int, http.Header, error) {
+	rec := httptest.NewRecorder()
+	h(rec, r)
+
+	res := rec.Result()
+	defer res.Body.Close()
+
+	p, err := Parse(rec.Body.String())
+	return p, res.StatusCode, res.Header, err
+}
@@ -0,0 +1,49 @@
+package surf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecord(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		_ = NewPatch().
+			AddSurface("#form", `<p class="error">Name &amp; email required</p>`).
+			AppendSurface("#log", "<li>rejected</li>").
+			WithStatus(http.StatusUnprocessableEntity).
+			WriteResponse(w)
+	}
+
+	p, status, header, err := Record(handler, httptest.NewRequest(http.MethodPost, "/save", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422, got %d", status)
+	}
+	if ct := header.Get("Content-Type"); ct != ContentType {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+
+	surfaces := p.Surfaces()
+	if len(surfaces) != 2 {
+		t.Fatalf("expected 2 surfaces, got %d", len(surfaces))
+	}
+	if surfaces[0].Target != "#form" || surfaces[0].Content != `<p class="error">Name &amp; email required</p>` {
+		t.Errorf("unexpected first surface: %+v", surfaces[0])
+	}
+	if surfaces[1].Target != "#log" || surfaces[1].Mode != ModeAppend {
+		t.Errorf("unexpected second surface: %+v", surfaces[1])
+	}
+}
+
+func TestRecordNotAPatch(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("plain"))
+	}
+
+	if _, _, _, err := Record(handler, httptest.NewRequest(http.MethodGet, "/", nil)); err != ErrNoPatch {
+		t.Errorf("expected ErrNoPatch, got %v", err)
+	}
+}
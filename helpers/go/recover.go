@@ -0,0 +1,31 @@
+package surf
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// ErrorTarget is the container RecoverPatch renders into
+var ErrorTarget = "#surf-error"
+
+// genericErrorMessage is shown by RecoverPatch outside debug mode
+const genericErrorMessage = "Something went wrong. Please try again."
+
+// RecoverPatch builds a 500 patch for a value recovered from a panic.
+// With debug set the escaped panic value and stack trace are shown;
+// otherwise only a generic message, so nothing internal leaks.
+func RecoverPatch(rec any, debug bool) *Patch {
+	content := `<p class="surf-error">` + genericErrorMessage + `</p>`
+	if debug {
+		content = fmt.Sprintf(`<p class="surf-error">panic: %s</p><pre>%s</pre>`,
+			escapeText(fmt.Sprint(rec)), escapeText(string(stack())))
+	}
+
+	return NewPatch().
+		AddTrusted(ErrorTarget, content).
+		WithStatus(http.StatusInternalServerError)
+}
+
+// stack is replaced in tests
+var stack = debug.Stack
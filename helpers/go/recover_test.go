@@ -0,0 +1,70 @@
+package surf
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func recovered(f func()) (rec any) {
+	defer func() { rec = recover() }()
+	f()
+	return nil
+}
+
+func TestRecoverPatchString(t *testing.T) {
+	rec := recovered(func() { panic("index <out> of range") })
+
+	html := RecoverPatch(rec, true).Render()
+	if !strings.Contains(html, `<surface target="#surf-error">`) {
+		t.Errorf("expected error container target: %s", html)
+	}
+	if !strings.Contains(html, "panic: index &lt;out&gt; of range") {
+		t.Errorf("expected escaped panic message: %s", html)
+	}
+}
+
+func TestRecoverPatchError(t *testing.T) {
+	rec := recovered(func() { panic(errors.New("db: connection refused")) })
+
+	p := RecoverPatch(rec, true)
+	if !strings.Contains(p.Render(), "panic: db: connection refused") {
+		t.Errorf("expected error message: %s", p.Render())
+	}
+	if p.statusCode() != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", p.statusCode())
+	}
+}
+
+func TestRecoverPatchDebugVsProduction(t *testing.T) {
+	stack = func() []byte { return []byte("goroutine 1 [running]:\nmain.handler()") }
+	t.Cleanup(func() { stack = defaultStack })
+
+	rec := recovered(func() { panic("secret token leaked") })
+
+	debugHTML := RecoverPatch(rec, true).Render()
+	if !strings.Contains(debugHTML, "secret token leaked") || !strings.Contains(debugHTML, "<pre>goroutine 1 [running]:") {
+		t.Errorf("debug output should include message and stack: %s", debugHTML)
+	}
+
+	prodHTML := RecoverPatch(rec, false).Render()
+	if strings.Contains(prodHTML, "secret") || strings.Contains(prodHTML, "goroutine") {
+		t.Errorf("production output leaks details: %s", prodHTML)
+	}
+	if !strings.Contains(prodHTML, genericErrorMessage) {
+		t.Errorf("production output should show the generic message: %s", prodHTML)
+	}
+}
+
+var defaultStack = stack
+
+func TestRecoverPatchStrictEscaping(t *testing.T) {
+	StrictEscaping = true
+	defer func() { StrictEscaping = false }()
+
+	html := RecoverPatch("boom <x>", true).Render()
+	if !strings.Contains(html, `<p class="surf-error">panic: boom &lt;x&gt;</p>`) {
+		t.Errorf("markup should not be escaped twice: %s", html)
+	}
+}
@@ -0,0 +1,503 @@
+package surf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const patchClose = "</d-patch>"
+
+// Renderer turns a patch into an output format.
+// Implementations may return partial output alongside an error.
+type Renderer interface {
+	Render(p *Patch) (string, error)
+}
+
+// HTMLRenderer renders the <d-patch> markup understood by the SURF client
+type HTMLRenderer struct{}
+
+// Render implements Renderer
+func (r HTMLRenderer) Render(p *Patch) (string, error) {
+	html, _, err := r.render(context.Background(), p)
+	return html, err
+}
+
+func (r HTMLRenderer) render(ctx context.Context, p *Patch) (string, []Surface, error) {
+	var sb strings.Builder
+	surfaces, err := r.renderInto(ctx, &sb, p)
+	return sb.String(), surfaces, err
+}
+
+func (r HTMLRenderer) renderInto(ctx context.Context, sb *strings.Builder, p *Patch) ([]Surface, error) {
+	if len(p.surfaces) == 1 && len(p.directives) == 0 {
+		return r.renderSingle(ctx, sb, p)
+	}
+	return r.renderAll(ctx, sb, p)
+}
+
+// renderSingle is the fast path for the common one-surface patch without
+// directives: sb is grown once to the estimated size and the output is
+// identical to renderAll's
+func (HTMLRenderer) renderSingle(ctx context.Context, sb *strings.Builder, p *Patch) ([]Surface, error) {
+	surfaces, err := p.Resolve(ctx)
+	open, eol := p.openTag(), p.opts.eol()
+
+	size := len(open) + len(eol) + len(patchClose)
+	if len(surfaces) == 1 {
+		size += p.estimateSurface(surfaces[0])
+	}
+	sb.Grow(size)
+
+	sb.WriteString(open)
+	sb.WriteString(eol)
+	if len(surfaces) == 1 {
+		writeSurface(sb, surfaces[0], &p.opts)
+	}
+	sb.WriteString(patchClose)
+	return surfaces, err
+}
+
+func (HTMLRenderer) renderAll(ctx context.Context, sb *strings.Builder, p *Patch) ([]Surface, error) {
+	surfaces, err := p.Resolve(ctx)
+	sb.WriteString(p.openTag())
+	if p.IsEmpty() {
+		sb.WriteString(patchClose)
+		return surfaces, err
+	}
+
+	sb.WriteString(p.opts.eol())
+	for _, s := range surfaces {
+		writeSurface(sb, s, &p.opts)
+	}
+	for _, d := range p.directives {
+		writeDirective(sb, d, &p.opts)
+	}
+	sb.WriteString(patchClose)
+	return surfaces, err
+}
+
+// openTag returns the <d-patch> opening tag with the root attributes
+func (p *Patch) openTag() string {
+	if p.seq == 0 && p.requestID == "" && !p.opts.timestamp {
+		return "<d-patch>"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<d-patch")
+	if p.seq != 0 {
+		sb.WriteString(p.opts.attr("seq", strconv.FormatUint(p.seq, 10)))
+	}
+	if p.requestID != "" {
+		sb.WriteString(p.opts.attr("request-id", escapeAttr(p.requestID)))
+	}
+	if p.opts.timestamp {
+		sb.WriteString(p.opts.attr("generated", now().UTC().Format(time.RFC3339)))
+	}
+	sb.WriteString(">")
+	return sb.String()
+}
+
+func writeSurface(sb *strings.Builder, s Surface, o *options) {
+	sb.WriteString("  ")
+	writeSurfaceElement(sb, s, o)
+	sb.WriteString(o.eol())
+}
+
+func writeSurfaceElement(sb *strings.Builder, s Surface, o *options) {
+	sb.WriteString("<surface")
+	if s.Slot != "" && s.Target == "" {
+		sb.WriteString(o.attr("slot", escapeAttr(s.Slot)))
+	} else if host, inner, ok := splitShadow(s.Target); ok {
+		sb.WriteString(o.attr("target", escapeHtml(host)))
+		sb.WriteString(o.attr("shadow", escapeHtml(inner)))
+	} else {
+		sb.WriteString(o.attr("target", escapeHtml(s.Target)))
+	}
+	if !s.isReplace() {
+		sb.WriteString(o.attr("mode", escapeHtml(string(s.Mode))))
+	}
+	if s.Mode == ModeAttr {
+		sb.WriteString(o.attr("attr", s.Attr))
+		sb.WriteString(o.attr("value", escapeAttr(s.Value)))
+	}
+	if s.Seq != 0 {
+		sb.WriteString(o.attr("seq", strconv.FormatUint(s.Seq, 10)))
+	}
+	if s.Slot != "" && s.Target != "" {
+		sb.WriteString(o.attr("slot", escapeAttr(s.Slot)))
+	}
+	if s.contentType != "" {
+		sb.WriteString(o.attr("content-type", escapeAttr(s.contentType)))
+	}
+	if o.checksums {
+		sb.WriteString(o.attr("checksum", fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(s.Content)))))
+	}
+	sb.WriteString(fmt.Sprintf(">%s</surface>", s.Content))
+}
+
+// JSONRenderer renders the patch as {"surfaces":[{"target":...,"content":...}]}
+type JSONRenderer struct{}
+
+// Render implements Renderer
+func (JSONRenderer) Render(p *Patch) (string, error) {
+	surfaces, err := p.Resolve(context.Background())
+	if surfaces == nil {
+		surfaces = []Surface{}
+	}
+
+	data, jsonErr := json.Marshal(struct {
+		Surfaces []Surface `json:"surfaces"`
+	}{surfaces})
+	if jsonErr != nil {
+		return "", jsonErr
+	}
+	return string(data), err
+}
+
+// RenderTimeoutError is returned by RenderWithTimeout when generators
+// exceed the render deadline
+type RenderTimeoutError struct {
+	Timeout time.Duration
+	// Completed lists the targets rendered before the deadline
+	Completed []string
+}
+
+func (e *RenderTimeoutError) Error() string {
+	return fmt.Sprintf("surf: render exceeded %s after %d surface(s)", e.Timeout, len(e.Completed))
+}
+
+func (e *RenderTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// Render generates the HTML for the patch.
+// Errors are ignored; unknown aliases are rendered as-is.
+func (p *Patch) Render() string {
+	html, _ := HTMLRenderer{}.Render(p)
+	return html
+}
+
+// RenderInto appends the output of Render to sb
+func (p *Patch) RenderInto(sb *strings.Builder) {
+	_, _ = HTMLRenderer{}.renderInto(context.Background(), sb, p)
+}
+
+// RenderSafe generates the HTML for the patch, returning an error
+// if any surface target cannot be resolved, a generator fails or Check
+// reports a problem
+func (p *Patch) RenderSafe() (string, error) {
+	if err := p.Check(); err != nil {
+		return "", err
+	}
+	html, err := HTMLRenderer{}.Render(p)
+	if err != nil {
+		return "", err
+	}
+	return html, nil
+}
+
+// ContentLength returns the exact byte length of Render's output
+func (p *Patch) ContentLength() int {
+	return len(p.Render())
+}
+
+// surfaceOverhead is the fixed markup around each surface, excluding the
+// line ending: `  <surface target="">` and `</surface>`
+const surfaceOverhead = len(`  <surface target="">`) + len("</surface>")
+
+// EstimatedSize approximates the rendered byte length without rendering.
+// It ignores escaping, alias resolution and generator output, so it is an
+// estimate; use ContentLength for the exact size.
+func (p *Patch) EstimatedSize() int {
+	size := len(p.openTag()) + len(patchClose)
+	if p.IsEmpty() {
+		return size
+	}
+
+	size += len(p.opts.eol())
+	for _, s := range p.surfaces {
+		size += p.estimateSurface(s)
+	}
+	return size + p.estimateDirectives()
+}
+
+func (p *Patch) estimateSurface(s Surface) int {
+	size := surfaceOverhead + len(p.opts.eol()) + len(s.Target) + len(s.Content)
+	if p.opts.checksums {
+		size += len(` checksum="00000000"`)
+	}
+	if s.Seq != 0 {
+		size += len(` seq=""`) + len(strconv.FormatUint(s.Seq, 10))
+	}
+	if !s.isReplace() {
+		size += len(` mode=""`) + len(s.Mode)
+	}
+	if s.Mode == ModeAttr {
+		size += len(` attr="" value=""`) + len(s.Attr) + len(s.Value)
+	}
+	return size
+}
+
+// estimateDirectives returns the rendered size of the directives, ignoring
+// escaping, as written by writeDirective
+func (p *Patch) estimateDirectives() int {
+	size := 0
+	for _, d := range p.directives {
+		size += len("  <>") + len(d.tag) + len(p.opts.eol())
+		for _, a := range d.attrs {
+			size += len(` =""`) + len(a.name) + len(a.value)
+		}
+		if !voidDirectives[d.tag] {
+			size += len("</>") + len(d.tag) + len(d.content)
+		}
+	}
+	return size
+}
+
+// Split partitions the surfaces into patches whose estimated size stays
+// under maxBytes, preserving order. A surface too large on its own gets a
+// patch to itself. The directives are kept together in the last patch,
+// which is a patch of its own when they do not fit. An empty patch yields
+// no patches.
+func (p *Patch) Split(maxBytes int) []*Patch {
+	var parts []*Patch
+	var current *Patch
+	size := 0
+
+	next := func(n int) {
+		if current == nil || size+n > maxBytes {
+			current = p.derive()
+			parts = append(parts, current)
+			size = len(p.openTag()) + len(p.opts.eol()) + len(patchClose)
+		}
+		size += n
+	}
+
+	for _, s := range p.surfaces {
+		next(p.estimateSurface(s))
+		current.surfaces = append(current.surfaces, s)
+	}
+	if len(p.directives) > 0 {
+		next(p.estimateDirectives())
+		current.directives = append([]directive(nil), p.directives...)
+	}
+	return parts
+}
+
+// RenderFiltered renders only the surfaces for which keep returns true,
+// in their original order. An empty patch is rendered if none match.
+func (p *Patch) RenderFiltered(keep func(Surface) bool) string {
+	return p.filter(keep).Render()
+}
+
+// RenderWithTags renders only the surfaces labelled with any of tags
+func (p *Patch) RenderWithTags(tags ...string) string {
+	return p.RenderFiltered(func(s Surface) bool {
+		for _, tag := range tags {
+			if s.HasTag(tag) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// filter returns a patch with the surfaces for which keep returns true
+func (p *Patch) filter(keep func(Surface) bool) *Patch {
+	filtered := p.derive()
+	for _, s := range p.surfaces {
+		if keep(s) {
+			filtered.surfaces = append(filtered.surfaces, s)
+		}
+	}
+	return filtered
+}
+
+// Fragments returns each surface as a standalone <surface> element, in
+// order, without the <d-patch> wrapper. Errors are ignored as in Render.
+func (p *Patch) Fragments() []string {
+	surfaces, _ := p.Resolve(context.Background())
+
+	fragments := make([]string, len(surfaces))
+	for i, s := range surfaces {
+		var sb strings.Builder
+		writeSurfaceElement(&sb, s, &p.opts)
+		fragments[i] = sb.String()
+	}
+	return fragments
+}
+
+// WriteTo renders the patch and writes it to w, implementing io.WriterTo.
+// Nothing is written if rendering fails.
+func (p *Patch) WriteTo(w io.Writer) (int64, error) {
+	html, err := p.RenderSafe()
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.WriteString(w, html)
+	return int64(n), err
+}
+
+// RenderWith renders the patch using r
+func (p *Patch) RenderWith(r Renderer) (string, error) {
+	return r.Render(p)
+}
+
+// RenderContext generates the HTML for the patch, passing ctx to generators.
+// Rendering stops when ctx is done; the returned HTML then holds the
+// surfaces completed so far alongside the context error.
+func (p *Patch) RenderContext(ctx context.Context) (string, error) {
+	html, _, err := HTMLRenderer{}.render(ctx, p)
+	return html, err
+}
+
+// RenderWithTimeout renders the patch within d.
+// If generators exceed d, the partial HTML is returned with a *RenderTimeoutError.
+func (p *Patch) RenderWithTimeout(d time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	html, surfaces, err := HTMLRenderer{}.render(ctx, p)
+	if errors.Is(err, context.DeadlineExceeded) {
+		completed := make([]string, len(surfaces))
+		for i, s := range surfaces {
+			completed[i] = s.Target
+		}
+		return html, &RenderTimeoutError{Timeout: d, Completed: completed}
+	}
+	return html, err
+}
+
+// SurfaceTransforms are applied in order to every surface when a patch is
+// rendered. They receive a copy, so stored surfaces are never changed.
+// Set them during initialization; they are not guarded for concurrent use.
+var SurfaceTransforms []func(Surface) Surface
+
+func applyTransforms(s Surface) Surface {
+	for _, transform := range SurfaceTransforms {
+		s = transform(s)
+	}
+	return s
+}
+
+// Resolve returns the surfaces as they will be rendered: aliases are
+// resolved, generators are run with ctx and SurfaceTransforms applied.
+// It is intended for custom Renderer implementations. When ctx is done, the
+// surfaces completed so far are returned alongside the context error.
+func (p *Patch) Resolve(ctx context.Context) ([]Surface, error) {
+	firstErr := p.err
+	resolved := make([]Surface, 0, len(p.surfaces))
+
+	for _, s := range p.surfaces {
+		if err := ctx.Err(); err != nil {
+			return resolved, err
+		}
+
+		target, err := resolveTarget(s.Target)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		s.Target = target
+
+		if s.gen != nil {
+			content, err := generate(ctx, s.gen)
+			if err != nil {
+				if ctx.Err() != nil {
+					return resolved, ctx.Err()
+				}
+				if firstErr == nil {
+					firstErr = fmt.Errorf("surf: surface %q: %w", target, err)
+				}
+				continue
+			}
+			s.Content = content
+			s.gen = nil
+		}
+
+		s = applyTransforms(s)
+		if p.opts.omitEmpty && s.Content == "" && s.carriesContent() {
+			continue
+		}
+		if limit := p.opts.maxSurfaceBytes; limit > 0 && len(s.Content) > limit && p.opts.overflow == OverflowTruncate {
+			s.Content = truncateUTF8(s.Content, limit) + TruncatedIndicator
+		}
+		if err := p.checkContent(s); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if prefix := p.opts.hydrationPrefix; prefix != "" && s.Content != "" && s.carriesContent() {
+			s.Content = "<!--" + prefix + ":start-->" + s.Content + "<!--" + prefix + ":end-->"
+		}
+		resolved = append(resolved, s)
+	}
+
+	return resolved, firstErr
+}
+
+// checkContent applies the content guards enabled by options.
+// Surfaces failing a guard are left out of the render.
+func (p *Patch) checkContent(s Surface) error {
+	if p.opts.noScripts && !s.allowScripts && containsFold(s.Content, "<script") {
+		return fmt.Errorf("%w: %q", ErrScriptContent, s.Target)
+	}
+	if limit := p.opts.maxSurfaceBytes; limit > 0 && len(s.Content) > limit && p.opts.overflow == OverflowError {
+		return fmt.Errorf("%w: %q has %d bytes, limit is %d", ErrSurfaceTooLarge, s.Target, len(s.Content), limit)
+	}
+	return nil
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// containsFold reports whether substr, which must be lowercase ASCII,
+// occurs in s ignoring ASCII case
+func containsFold(s, substr string) bool {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// generate runs gen, returning early if ctx is done before it finishes
+func generate(ctx context.Context, gen func(ctx context.Context) (string, error)) (string, error) {
+	if ctx.Done() == nil {
+		return gen(ctx)
+	}
+
+	type result struct {
+		content string
+		err     error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		content, err := gen(ctx)
+		ch <- result{content, err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.content, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
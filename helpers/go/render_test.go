@@ -0,0 +1,276 @@
+package surf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type targetListRenderer struct{}
+
+func (targetListRenderer) Render(p *Patch) (string, error) {
+	surfaces, err := p.Resolve(context.Background())
+	targets := make([]string, len(surfaces))
+	for i, s := range surfaces {
+		targets[i] = s.Target
+	}
+	return strings.Join(targets, ","), err
+}
+
+func TestRenderWith(t *testing.T) {
+	p := NewPatch().
+		AddSurface("#main", "<h1>Hi</h1>").
+		AddSurface("#toast", "Saved")
+
+	html, err := p.RenderWith(HTMLRenderer{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if html != p.Render() {
+		t.Errorf("HTMLRenderer should match Render:\n%s\n%s", html, p.Render())
+	}
+
+	out, err := p.RenderWith(JSONRenderer{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded struct {
+		Surfaces []Surface `json:"surfaces"`
+	}
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	if len(decoded.Surfaces) != 2 || decoded.Surfaces[0].Target != "#main" || decoded.Surfaces[1].Content != "Saved" {
+		t.Errorf("unexpected JSON: %s", out)
+	}
+
+	custom, _ := p.RenderWith(targetListRenderer{})
+	if custom != "#main,#toast" {
+		t.Errorf("unexpected custom render: %s", custom)
+	}
+}
+
+func TestEstimatedSize(t *testing.T) {
+	p := NewPatch().
+		AddSurface("#main", "<h1>Dashboard</h1><p>Welcome back</p>").
+		AppendSurface("#log", "<li>entry</li>").
+		InsertAfter(`[data-id="7"]`, "<li>7.5</li>")
+
+	actual := p.ContentLength()
+	estimate := p.EstimatedSize()
+
+	// Escaping the quoted target adds a few bytes the estimate ignores
+	if diff := actual - estimate; diff < 0 || diff > 16 {
+		t.Errorf("estimate %d too far from actual %d", estimate, actual)
+	}
+
+	if empty := NewPatch(); empty.EstimatedSize() != empty.ContentLength() {
+		t.Errorf("empty patch estimate should be exact")
+	}
+	if state := NewPatch().AddState("cart", map[string]int{"items": 3}); state.EstimatedSize() != state.ContentLength() {
+		t.Errorf("directive estimate %d should match actual %d", state.EstimatedSize(), state.ContentLength())
+	}
+}
+
+func TestSplit(t *testing.T) {
+	p := NewPatch().
+		AddSurface("#a", strings.Repeat("a", 40)).
+		AddSurface("#b", strings.Repeat("b", 40)).
+		AddSurface("#c", strings.Repeat("c", 40))
+
+	parts := p.Split(200)
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 parts, got %d", len(parts))
+	}
+
+	var targets []string
+	for _, part := range parts {
+		if n := part.ContentLength(); n > 200 {
+			t.Errorf("part rendered %d bytes, over the limit", n)
+		}
+		for _, s := range part.Surfaces() {
+			targets = append(targets, s.Target)
+		}
+	}
+	if strings.Join(targets, ",") != "#a,#b,#c" {
+		t.Errorf("order not preserved: %v", targets)
+	}
+}
+
+func TestSplitOversizedSurface(t *testing.T) {
+	p := NewPatch().
+		AddSurface("#small", "x").
+		AddSurface("#huge", strings.Repeat("h", 500)).
+		AddSurface("#tail", "y")
+
+	parts := p.Split(100)
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts, got %d", len(parts))
+	}
+	if s := parts[1].Surfaces(); len(s) != 1 || s[0].Target != "#huge" {
+		t.Errorf("oversized surface should be alone: %+v", s)
+	}
+}
+
+func TestSplitKeepsDirectives(t *testing.T) {
+	p := NewPatch().
+		AddSurface("#a", strings.Repeat("a", 40)).
+		AddSurface("#b", strings.Repeat("b", 40)).
+		AddState("cart", 3).
+		SetMeta("description", "Cart")
+
+	parts := p.Split(120)
+	if len(parts) < 2 {
+		t.Fatalf("expected several parts, got %d", len(parts))
+	}
+	for i, part := range parts {
+		html := part.Render()
+		last := i == len(parts)-1
+		if strings.Contains(html, `<state key="cart">3</state>`) != last || strings.Contains(html, `<meta name="description"`) != last {
+			t.Errorf("part %d should carry the directives only if last:\n%s", i, html)
+		}
+	}
+
+	only := NewPatch().AddState("cart", 3).Split(1000)
+	if len(only) != 1 || only[0].Render() != NewPatch().AddState("cart", 3).Render() {
+		t.Errorf("directive-only patch should split into itself: %d parts", len(only))
+	}
+}
+
+func TestRenderFiltered(t *testing.T) {
+	p := NewPatch().
+		AddSurface("#nav-main", "main").
+		AppendSurface("#feed", "<li>1</li>").
+		AddSurface("#nav-footer", "footer")
+
+	nav := p.RenderFiltered(func(s Surface) bool { return strings.HasPrefix(s.Target, "#nav-") })
+	expected := "<d-patch>\n" +
+		"  <surface target=\"#nav-main\">main</surface>\n" +
+		"  <surface target=\"#nav-footer\">footer</surface>\n" +
+		"</d-patch>"
+	if nav != expected {
+		t.Errorf("unexpected prefix filter render:\n%s", nav)
+	}
+
+	appends := p.RenderFiltered(func(s Surface) bool { return s.Mode == ModeAppend })
+	if !strings.Contains(appends, `<surface target="#feed" mode="append">`) || strings.Contains(appends, "#nav") {
+		t.Errorf("unexpected mode filter render:\n%s", appends)
+	}
+
+	if none := p.RenderFiltered(func(Surface) bool { return false }); none != "<d-patch></d-patch>" {
+		t.Errorf("expected empty patch, got %s", none)
+	}
+}
+
+func TestFragments(t *testing.T) {
+	p := NewPatch().
+		AddSurface("#main", "<h1>Hi</h1>").
+		AppendSurface("#list", "<li>1</li>").
+		SetAttr("#btn", "disabled", "true")
+
+	fragments := p.Fragments()
+	lines := strings.Split(p.Render(), "\n")
+	lines = lines[1 : len(lines)-1]
+
+	if len(fragments) != 3 || len(lines) != 3 {
+		t.Fatalf("expected 3 fragments, got %d", len(fragments))
+	}
+	for i, fragment := range fragments {
+		if fragment != strings.TrimSpace(lines[i]) {
+			t.Errorf("fragment %d mismatch:\n%s\n%s", i, fragment, lines[i])
+		}
+	}
+}
+
+func TestSurfaceTransforms(t *testing.T) {
+	SurfaceTransforms = []func(Surface) Surface{
+		func(s Surface) Surface {
+			s.Content = strings.ReplaceAll(s.Content, `src="/`, `src="https://cdn/`)
+			return s
+		},
+	}
+	t.Cleanup(func() { SurfaceTransforms = nil })
+
+	p := NewPatch().AddSurface("#hero", `<img src="/logo.png">`)
+
+	var sb strings.Builder
+	if _, err := p.WriteTo(&sb); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, out := range []string{p.Render(), sb.String()} {
+		if !strings.Contains(out, `<img src="https://cdn/logo.png">`) {
+			t.Errorf("transform not applied: %s", out)
+		}
+	}
+	if p.Surfaces()[0].Content != `<img src="/logo.png">` {
+		t.Errorf("stored surface was mutated: %s", p.Surfaces()[0].Content)
+	}
+}
+
+func TestRenderInto(t *testing.T) {
+	p := NewPatch().AddSurface("#main", "<p>Hi</p>").AddState("n", 1)
+
+	var sb strings.Builder
+	sb.WriteString("<!-- prefix -->")
+	p.RenderInto(&sb)
+
+	if sb.String() != "<!-- prefix -->"+p.Render() {
+		t.Errorf("unexpected output:\n%s", sb.String())
+	}
+}
+
+func TestRenderSingleMatchesGeneralPath(t *testing.T) {
+	RegisterTarget("@single", "#resolved")
+	t.Cleanup(func() { unregisterTarget("@single") })
+
+	patches := map[string]*Patch{
+		"replace":   NewPatch().AddSurface("#a", "<p>1</p>"),
+		"append":    NewPatch().AppendSurface("#list", "<li>x</li>"),
+		"attr":      NewPatch().SetAttr("#a", "title", `"q"`),
+		"remove":    NewPatch().RemoveTarget("#gone"),
+		"alias":     NewPatch().AddSurface("@single", "x"),
+		"generator": NewPatch().AddSurfaceFunc("#g", func(context.Context) (string, error) { return "gen", nil }),
+		"omitted":   NewPatch(WithOmitEmpty()).AddSurface("#a", ""),
+		"options":   NewPatch(WithChecksums(), WithLineEnding("\r\n"), WithAttrQuote('\'')).AddSurface("#a", "1"),
+		"root":      NewPatch().WithSequence(4).WithRequestID("r").AddSurfaceSeq("#a", "1", 2),
+		"slot":      NewPatch().AddSlot("header", "h"),
+	}
+	for name, p := range patches {
+		t.Run(name, func(t *testing.T) {
+			var fast, general strings.Builder
+			_, fastErr := HTMLRenderer{}.renderSingle(context.Background(), &fast, p)
+			_, generalErr := HTMLRenderer{}.renderAll(context.Background(), &general, p)
+
+			if fast.String() != general.String() {
+				t.Errorf("fast path %q differs from general path %q", fast.String(), general.String())
+			}
+			if !errors.Is(fastErr, generalErr) {
+				t.Errorf("fast path error %v differs from %v", fastErr, generalErr)
+			}
+			if p.Render() != general.String() {
+				t.Errorf("Render should use the fast path output, got %q", p.Render())
+			}
+		})
+	}
+}
+
+func BenchmarkRenderSingleSurface(b *testing.B) {
+	p := NewPatch().AddSurface("#main", strings.Repeat("<p>content</p>", 20))
+
+	b.Run("fast", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var sb strings.Builder
+			_, _ = HTMLRenderer{}.renderSingle(context.Background(), &sb, p)
+		}
+	})
+	b.Run("general", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var sb strings.Builder
+			_, _ = HTMLRenderer{}.renderAll(context.Background(), &sb, p)
+		}
+	})
+}
@@ -0,0 +1,21 @@
+package surf
+
+import "net/http"
+
+// RequestIDHeader is the request header FromRequest reads the correlation
+// id from. Set it during initialization to use another header.
+var RequestIDHeader = "X-Request-ID"
+
+// WithRequestID sets the request-id attribute of the <d-patch> element so
+// client logs can be correlated with server logs. An empty id removes it.
+func (p *Patch) WithRequestID(id string) *Patch {
+	p = p.mutable()
+	p.requestID = id
+	return p
+}
+
+// FromRequest creates a new Patch carrying the correlation id from the
+// RequestIDHeader of r, if any
+func FromRequest(r *http.Request) *Patch {
+	return NewPatch().WithRequestID(r.Header.Get(RequestIDHeader))
+}
@@ -0,0 +1,51 @@
+package surf
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithRequestID(t *testing.T) {
+	html := NewPatch().WithSequence(3).WithRequestID(`a"b`).AddSurface("#a", "1").Render()
+
+	want := "<d-patch seq=\"3\" request-id=\"a&quot;b\">\n  <surface target=\"#a\">1</surface>\n</d-patch>"
+	if html != want {
+		t.Errorf("got %q, want %q", html, want)
+	}
+
+	p, err := Parse(html)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.requestID != `a"b` {
+		t.Errorf("Parse should keep the request id, got %q", p.requestID)
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Request-ID", "req-42")
+
+	html := FromRequest(r).Render()
+	if want := `<d-patch request-id="req-42"></d-patch>`; html != want {
+		t.Errorf("got %q, want %q", html, want)
+	}
+}
+
+func TestFromRequestWithoutHeader(t *testing.T) {
+	html := FromRequest(httptest.NewRequest("GET", "/", nil)).Render()
+	if html != "<d-patch></d-patch>" {
+		t.Errorf("missing header should add no attribute, got %q", html)
+	}
+}
+
+func TestFromRequestCustomHeader(t *testing.T) {
+	defer func(h string) { RequestIDHeader = h }(RequestIDHeader)
+	RequestIDHeader = "X-Correlation-ID"
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Correlation-ID", "c-1")
+	if got := FromRequest(r).requestID; got != "c-1" {
+		t.Errorf("got %q, want c-1", got)
+	}
+}
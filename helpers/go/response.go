@@ -0,0 +1,65 @@
+package surf
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// Response declares the status, headers and cookies sent with a patch
+type Response struct {
+	patch   *Patch
+	status  int
+	header  http.Header
+	cookies []*http.Cookie
+}
+
+// Respond starts a Response for the patch, using its status by default
+func (p *Patch) Respond() *Response {
+	return &Response{
+		patch:  p,
+		status: p.statusCode(),
+		header: make(http.Header),
+	}
+}
+
+// Status sets the HTTP status
+func (r *Response) Status(code int) *Response {
+	r.status = code
+	return r
+}
+
+// Header sets a response header, overriding the patch defaults
+func (r *Response) Header(key, value string) *Response {
+	r.header.Set(key, value)
+	return r
+}
+
+// Cookie adds a Set-Cookie header
+func (r *Response) Cookie(c *http.Cookie) *Response {
+	r.cookies = append(r.cookies, c)
+	return r
+}
+
+// Write renders the patch and writes the complete response to w.
+// Nothing is written if rendering fails.
+func (r *Response) Write(w http.ResponseWriter) error {
+	start := writeStart()
+	var body bytes.Buffer
+	if _, err := r.patch.WriteTo(&body); err != nil {
+		return err
+	}
+
+	h := w.Header()
+	r.patch.setHeaders(h)
+	for key, values := range r.header {
+		h[key] = append([]string(nil), values...)
+	}
+	for _, c := range r.cookies {
+		http.SetCookie(w, c)
+	}
+
+	w.WriteHeader(r.status)
+	n, err := body.WriteTo(w)
+	notifyWrite(start, w, r.status, int(n))
+	return err
+}
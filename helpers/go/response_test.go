@@ -0,0 +1,45 @@
+package surf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRespond(t *testing.T) {
+	p := NewPatch().AddSurface("#session", "Signed in")
+
+	rec := httptest.NewRecorder()
+	err := p.Respond().
+		Status(http.StatusAccepted).
+		Header("Cache-Control", "no-store").
+		Cookie(&http.Cookie{Name: "sid", Value: "abc123", HttpOnly: true}).
+		Write(rec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res := rec.Result()
+	if res.StatusCode != http.StatusAccepted {
+		t.Errorf("expected 202, got %d", res.StatusCode)
+	}
+	if res.Header.Get("Cache-Control") != "no-store" || res.Header.Get("Content-Type") != ContentType {
+		t.Errorf("unexpected headers: %v", res.Header)
+	}
+	cookies := res.Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "sid" || cookies[0].Value != "abc123" || !cookies[0].HttpOnly {
+		t.Errorf("unexpected cookies: %v", cookies)
+	}
+	if rec.Body.String() != p.Render() {
+		t.Errorf("unexpected body: %s", rec.Body.String())
+	}
+}
+
+func TestRespondDefaultsToPatchStatus(t *testing.T) {
+	rec := httptest.NewRecorder()
+	_ = NewPatch().WithStatus(http.StatusCreated).Respond().Write(rec)
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,32 @@
+package surf
+
+import "sync/atomic"
+
+// Sequence hands out increasing sequence numbers, starting at 1.
+// It is safe for concurrent use; the zero value is ready to use.
+type Sequence struct {
+	n atomic.Uint64
+}
+
+// Next returns the next sequence number
+func (s *Sequence) Next() uint64 {
+	return s.n.Add(1)
+}
+
+// WithSequence sets the seq attribute of the <d-patch> element. The client
+// applies a patch only if its sequence is newer than the last one applied.
+func (p *Patch) WithSequence(seq uint64) *Patch {
+	p = p.mutable()
+	p.seq = seq
+	return p
+}
+
+// AddSurfaceSeq adds a surface update carrying its own seq attribute, which
+// the client compares per target to drop stale updates
+func (p *Patch) AddSurfaceSeq(target, content string, seq uint64) *Patch {
+	return p.add(Surface{
+		Target:  target,
+		Content: strictText(content),
+		Seq:     seq,
+	})
+}
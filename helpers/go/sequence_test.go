@@ -0,0 +1,44 @@
+package surf
+
+import "testing"
+
+func TestWithSequence(t *testing.T) {
+	var seq Sequence
+
+	first := NewPatch().WithSequence(seq.Next()).AddSurface("#count", "1").Render()
+	second := NewPatch().WithSequence(seq.Next()).Render()
+
+	if first != "<d-patch seq=\"1\">\n  <surface target=\"#count\">1</surface>\n</d-patch>" {
+		t.Errorf("unexpected first patch:\n%s", first)
+	}
+	if second != `<d-patch seq="2"></d-patch>` {
+		t.Errorf("unexpected second patch: %s", second)
+	}
+}
+
+func TestAddSurfaceSeq(t *testing.T) {
+	p := NewPatch().AddSurfaceSeq("#price", "10", 41).AddSurfaceSeq("#price", "12", 42)
+
+	expected := "<d-patch>\n" +
+		"  <surface target=\"#price\" seq=\"41\">10</surface>\n" +
+		"  <surface target=\"#price\" seq=\"42\">12</surface>\n" +
+		"</d-patch>"
+	if html := p.Render(); html != expected {
+		t.Errorf("unexpected render:\n%s", html)
+	}
+	if p.EstimatedSize() != p.ContentLength() {
+		t.Errorf("estimate %d should match %d", p.EstimatedSize(), p.ContentLength())
+	}
+}
+
+func TestParseSequence(t *testing.T) {
+	original := NewPatch().WithSequence(7).AddSurfaceSeq("#a", "x", 3)
+
+	p, err := Parse(original.Render())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Render() != original.Render() {
+		t.Errorf("round trip mismatch:\n%s", p.Render())
+	}
+}
@@ -0,0 +1,24 @@
+package surf
+
+import "strings"
+
+// shadowSeparator joins the host and inner selectors of a shadow target.
+// It is not valid CSS, so it cannot collide with an ordinary selector.
+const shadowSeparator = " >>> "
+
+// ByShadow returns a target that selects innerSelector inside the open
+// shadow root of the element matching hostSelector. It may be used
+// wherever a target is accepted.
+//
+// Such surfaces render as target="<host>" shadow="<inner>". The client
+// resolves the host with querySelector(target) and then applies the surface
+// to host.shadowRoot.querySelector(shadow), skipping the surface when the
+// host has no open shadow root. Only one shadow boundary can be crossed.
+func ByShadow(hostSelector, innerSelector string) string {
+	return hostSelector + shadowSeparator + innerSelector
+}
+
+// splitShadow splits a ByShadow target into its host and inner selectors
+func splitShadow(target string) (host, inner string, ok bool) {
+	return strings.Cut(target, shadowSeparator)
+}
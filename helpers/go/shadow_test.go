@@ -0,0 +1,53 @@
+package surf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestByShadow(t *testing.T) {
+	target := ByShadow("my-widget", ".count")
+	html := NewPatch().AddSurface(target, "3").AppendSurface("#plain", "x").Render()
+
+	expected := "<d-patch>\n" +
+		"  <surface target=\"my-widget\" shadow=\".count\">3</surface>\n" +
+		"  <surface target=\"#plain\" mode=\"append\">x</surface>\n" +
+		"</d-patch>"
+	if html != expected {
+		t.Errorf("unexpected render:\n%s", html)
+	}
+
+	p, err := Parse(html)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := p.Surfaces()[0].Target; got != target {
+		t.Errorf("Parse should restore the shadow target, got %q", got)
+	}
+}
+
+func TestByShadowEscapesSelectors(t *testing.T) {
+	html := NewPatch().AddSurface(ByShadow(`x-card[data-id="1"]`, `[name="q"]`), "").Render()
+	want := `<surface target="x-card[data-id=&quot;1&quot;]" shadow="[name=&quot;q&quot;]">`
+	if !strings.Contains(html, want) {
+		t.Errorf("expected %s in:\n%s", want, html)
+	}
+}
+
+func TestByShadowAliasHost(t *testing.T) {
+	RegisterTarget("@widget", "my-widget")
+	t.Cleanup(func() { unregisterTarget("@widget") })
+
+	html, err := NewPatch().AddSurface(ByShadow("@widget", ".count"), "3").RenderSafe()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(html, `<surface target="my-widget" shadow=".count">3</surface>`) {
+		t.Errorf("shadow host alias not resolved: %s", html)
+	}
+
+	if _, err := NewPatch().AddSurface(ByShadow("@missing", ".count"), "3").RenderSafe(); !errors.Is(err, ErrUnknownTarget) {
+		t.Errorf("expected ErrUnknownTarget, got %v", err)
+	}
+}
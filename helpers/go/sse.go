@@ -0,0 +1,141 @@
+package surf
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// EventStreamContentType is the Content-Type header for SSE streams
+const EventStreamContentType = "text/event-stream"
+
+// Multiplexer merges patches from named channels into one SSE stream.
+// Each event carries its channel name in the event field so the client can
+// route it. A Multiplexer serves a single client connection.
+type Multiplexer struct {
+	mu       sync.Mutex
+	channels map[string]chan *Patch
+	events   chan sseEvent
+	done     chan struct{}
+	once     sync.Once
+}
+
+type sseEvent struct {
+	name  string
+	patch *Patch
+}
+
+// NewMultiplexer creates a new Multiplexer
+func NewMultiplexer() *Multiplexer {
+	return &Multiplexer{
+		channels: make(map[string]chan *Patch),
+		events:   make(chan sseEvent),
+		done:     make(chan struct{}),
+	}
+}
+
+// Channel returns the channel for name, creating it on first use.
+// CR and LF are removed from name, as they would end the event field.
+// Producers should stop sending once Done is closed.
+func (m *Multiplexer) Channel(name string) chan<- *Patch {
+	name = lineBreaks.Replace(name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if ch, ok := m.channels[name]; ok {
+		return ch
+	}
+	ch := make(chan *Patch)
+	m.channels[name] = ch
+	go m.forward(name, ch)
+	return ch
+}
+
+// Done is closed when the client disconnects
+func (m *Multiplexer) Done() <-chan struct{} {
+	return m.done
+}
+
+// forward passes patches from ch to the stream until the client disconnects
+func (m *Multiplexer) forward(name string, ch chan *Patch) {
+	for {
+		select {
+		case p := <-ch:
+			select {
+			case m.events <- sseEvent{name, p}:
+			case <-m.done:
+				return
+			}
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// ServeSSE streams patches from every channel to w as server-sent events
+// until the request context is done, then closes Done and drops the
+// channels. Patches that fail to render are skipped.
+func (m *Multiplexer) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	defer m.close()
+
+	h := w.Header()
+	h.Set("Content-Type", EventStreamContentType)
+	h.Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	for {
+		select {
+		case ev := <-m.events:
+			html, err := ev.patch.RenderSafe()
+			if err != nil {
+				continue
+			}
+			if _, err := w.Write([]byte(formatEvent(ev.name, html))); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (m *Multiplexer) close() {
+	m.once.Do(func() {
+		close(m.done)
+		m.mu.Lock()
+		m.channels = make(map[string]chan *Patch)
+		m.mu.Unlock()
+	})
+}
+
+// lineBreaks removes the characters that end an SSE line
+var lineBreaks = strings.NewReplacer("\r", "", "\n", "")
+
+// formatEvent formats data as an SSE event named name, one data field per
+// line of data. CRLF and lone CR end lines in SSE too, so they are
+// normalized to LF before splitting.
+func formatEvent(name, data string) string {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+
+	var sb strings.Builder
+	sb.WriteString("event: ")
+	sb.WriteString(name)
+	sb.WriteByte('\n')
+	for _, line := range strings.Split(strings.TrimSuffix(data, "\n"), "\n") {
+		sb.WriteString("data: ")
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+	sb.WriteByte('\n')
+	return sb.String()
+}
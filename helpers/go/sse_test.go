@@ -0,0 +1,92 @@
+package surf
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMultiplexerTagsEvents(t *testing.T) {
+	m := NewMultiplexer()
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest("GET", "/events", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	served := make(chan struct{})
+	go func() {
+		m.ServeSSE(w, r)
+		close(served)
+	}()
+
+	m.Channel("clock") <- NewPatch().AddSurface("#clock", "12:00")
+	m.Channel("stock") <- NewPatch().AddSurface("#stock", "42")
+	// The channels are unbuffered, so a second send on a channel only
+	// completes once the stream has taken its previous event
+	m.Channel("clock") <- NewPatch().AddSurface("#clock", "12:01")
+	m.Channel("stock") <- NewPatch().AddSurface("#stock", "43")
+	cancel()
+
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Fatal("ServeSSE did not return on disconnect")
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != EventStreamContentType {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"event: clock\ndata: <d-patch>\ndata:   <surface target=\"#clock\">12:00</surface>\ndata: </d-patch>\n\n",
+		"event: stock\ndata: <d-patch>\ndata:   <surface target=\"#stock\">42</surface>\ndata: </d-patch>\n\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing event %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestMultiplexerCleansUpOnDisconnect(t *testing.T) {
+	m := NewMultiplexer()
+	ch := m.Channel("a")
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest("GET", "/events", nil).WithContext(ctx)
+
+	cancel()
+	m.ServeSSE(httptest.NewRecorder(), r)
+
+	select {
+	case <-m.Done():
+	default:
+		t.Fatal("Done should be closed after disconnect")
+	}
+	if len(m.channels) != 0 {
+		t.Errorf("channels should be dropped, got %d", len(m.channels))
+	}
+
+	select {
+	case ch <- NewPatch():
+		t.Error("send should not be accepted after disconnect")
+	case <-m.Done():
+	}
+}
+
+func TestFormatEventLineBreaks(t *testing.T) {
+	got := formatEvent("feed", "a\r\nb\rid: 99\revent: admin\n")
+	want := "event: feed\ndata: a\ndata: b\ndata: id: 99\ndata: event: admin\n\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMultiplexerSanitizesChannelNames(t *testing.T) {
+	m := NewMultiplexer()
+	m.Channel("feed\r\nevent: admin")
+
+	if _, ok := m.channels["feedevent: admin"]; !ok || len(m.channels) != 1 {
+		t.Errorf("line breaks should be removed from channel names, got %v", m.channels)
+	}
+	m.close()
+}
@@ -0,0 +1,94 @@
+package surf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidSelector is returned when a target is not a plausible CSS selector
+var ErrInvalidSelector = errors.New("surf: invalid selector")
+
+// StrictPatch wraps a Patch and rejects invalid targets when they are added
+// rather than at render time
+type StrictPatch struct {
+	patch *Patch
+}
+
+// NewStrictPatch creates a new StrictPatch
+func NewStrictPatch() *StrictPatch {
+	return &StrictPatch{patch: NewPatch()}
+}
+
+// AddSurface adds a surface update, returning an error if target is not a
+// valid selector. Nothing is added on error.
+func (sp *StrictPatch) AddSurface(target, content string) error {
+	if err := validateSelector(target); err != nil {
+		return err
+	}
+	sp.patch.AddSurface(target, content)
+	return nil
+}
+
+// Patch returns the underlying Patch
+func (sp *StrictPatch) Patch() *Patch {
+	return sp.patch
+}
+
+// validateSelector performs a basic syntax check: the selector must be
+// non-empty, brackets and parentheses must balance, and quotes may only
+// appear, closed, inside them
+func validateSelector(selector string) error {
+	if strings.TrimSpace(selector) == "" {
+		return fmt.Errorf("%w: empty selector", ErrInvalidSelector)
+	}
+
+	var stack []byte
+	for i := 0; i < len(selector); i++ {
+		c := selector[i]
+		switch c {
+		case '\\':
+			i++
+		case '[', '(':
+			stack = append(stack, c)
+		case ']', ')':
+			open := byte('[')
+			if c == ')' {
+				open = '('
+			}
+			if len(stack) == 0 || stack[len(stack)-1] != open {
+				return fmt.Errorf("%w: unbalanced %q in %q", ErrInvalidSelector, c, selector)
+			}
+			stack = stack[:len(stack)-1]
+		case '"', '\'':
+			if len(stack) == 0 {
+				return fmt.Errorf("%w: stray quote in %q", ErrInvalidSelector, selector)
+			}
+			end := closingQuote(selector, i)
+			if end < 0 {
+				return fmt.Errorf("%w: unterminated string in %q", ErrInvalidSelector, selector)
+			}
+			i = end
+		}
+	}
+
+	if len(stack) > 0 {
+		return fmt.Errorf("%w: unclosed %q in %q", ErrInvalidSelector, stack[len(stack)-1], selector)
+	}
+	return nil
+}
+
+// closingQuote returns the index of the quote closing the string opened at
+// start, or -1
+func closingQuote(s string, start int) int {
+	quote := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			return i
+		}
+	}
+	return -1
+}
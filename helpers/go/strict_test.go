@@ -0,0 +1,47 @@
+package surf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStrictPatchValid(t *testing.T) {
+	sp := NewStrictPatch()
+	for _, target := range []string{
+		"#main",
+		".list > li:nth-child(2)",
+		`[data-id="a]b"]`,
+		`input[name='q']:not(.hidden)`,
+		"@main",
+	} {
+		if err := sp.AddSurface(target, "x"); err != nil {
+			t.Errorf("AddSurface(%q) failed: %v", target, err)
+		}
+	}
+
+	if n := strings.Count(sp.Patch().Render(), "<surface "); n != 5 {
+		t.Errorf("expected 5 surfaces, got %d", n)
+	}
+}
+
+func TestStrictPatchInvalid(t *testing.T) {
+	sp := NewStrictPatch()
+	for _, target := range []string{
+		"",
+		"   ",
+		"[data-id=1",
+		"div)",
+		"li:not(.a]",
+		`#main"`,
+		`[title="open]`,
+	} {
+		if err := sp.AddSurface(target, "x"); !errors.Is(err, ErrInvalidSelector) {
+			t.Errorf("AddSurface(%q): expected ErrInvalidSelector, got %v", target, err)
+		}
+	}
+
+	if len(sp.Patch().Surfaces()) != 0 {
+		t.Errorf("invalid surfaces should not be added")
+	}
+}
@@ -0,0 +1,70 @@
+package surf
+
+import (
+	"context"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// InlineStyles returns the inline CSS in the rendered surfaces, for
+// building style-src hashes under a strict Content-Security-Policy: the
+// text of each <style> element exactly as sent, each style attribute value
+// as the browser parses it, and the Value of attr surfaces setting style.
+// Styles loaded through <link> and CSS set by scripts are not included.
+// Duplicates are reported once, in order of first occurrence. The patch
+// is not modified; errors are ignored as in Render.
+func (p *Patch) InlineStyles() []string {
+	surfaces, _ := p.Resolve(context.Background())
+
+	var styles []string
+	seen := make(map[string]bool)
+	add := func(style string) {
+		if style != "" && !seen[style] {
+			seen[style] = true
+			styles = append(styles, style)
+		}
+	}
+
+	for _, s := range surfaces {
+		if s.Mode == ModeAttr {
+			if strings.EqualFold(s.Attr, "style") {
+				add(s.Value)
+			}
+			continue
+		}
+		for _, style := range inlineStyles(s.Content) {
+			add(style)
+		}
+	}
+	return styles
+}
+
+// inlineStyles returns the <style> contents and style attribute values
+// in content, in document order
+func inlineStyles(content string) []string {
+	var styles []string
+	z := html.NewTokenizer(strings.NewReader(content))
+	inStyle := false
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return styles
+		case html.TextToken:
+			if inStyle {
+				styles = append(styles, string(z.Raw()))
+			}
+		case html.EndTagToken:
+			inStyle = false
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			inStyle = token.Data == "style" && token.Type == html.StartTagToken
+			for _, a := range token.Attr {
+				if a.Namespace == "" && a.Key == "style" {
+					styles = append(styles, a.Val)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package surf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInlineStylesBlock(t *testing.T) {
+	p := NewPatch().AddSurface("#a", "<style>.card > p { color: red; }</style><div class=\"card\"><p>x</p></div>")
+
+	want := []string{".card > p { color: red; }"}
+	if got := p.InlineStyles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestInlineStylesAttributes(t *testing.T) {
+	content := `<div style="color: blue">a</div><span style='margin:0 &amp; 0'>b</span><div style="color: blue">c</div>`
+	p := NewPatch().
+		AddSurface("#a", content).
+		SetAttr("#b", "style", "display:none").
+		SetAttr("#c", "class", "x")
+
+	want := []string{"color: blue", "margin:0 & 0", "display:none"}
+	if got := p.InlineStyles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if p.Surfaces()[0].Content != content {
+		t.Error("content must not be modified")
+	}
+}
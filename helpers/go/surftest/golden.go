@@ -0,0 +1,111 @@
+// Package surftest provides test helpers for code that builds SURF patches
+package surftest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	surf "github.com/berkan-cetinkaya/surf/helpers/go"
+)
+
+// update is registered under a package-specific name so that importing
+// surftest does not clash with a test package defining its own -update
+var update = flag.Bool("surftest.update", false, "rewrite golden files with the rendered patches")
+
+// updating reports whether golden files should be rewritten: with
+// -surftest.update, or with an -update bool flag defined by the test package
+func updating() bool {
+	if *update {
+		return true
+	}
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return false
+	}
+	v, _ := getter.Get().(bool)
+	return v
+}
+
+// AssertGolden renders p with RenderSafe and compares the result to the
+// golden file at path, reporting a line diff on mismatch. With the
+// -surftest.update test flag, or an -update flag defined by the test
+// package, the golden file, and any missing directories, is written instead.
+func AssertGolden(t testing.TB, p *surf.Patch, path string) {
+	t.Helper()
+
+	got, err := p.RenderSafe()
+	if err != nil {
+		t.Fatalf("surftest: rendering patch: %v", err)
+		return
+	}
+
+	if updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("surftest: %v", err)
+			return
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("surftest: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("surftest: reading golden file (run with -surftest.update to create it): %v", err)
+		return
+	}
+	if !bytes.Equal(want, []byte(got)) {
+		t.Errorf("surftest: patch does not match %s (-want +got):\n%s", path, diffLines(string(want), got))
+	}
+}
+
+// diffLines returns the lines removed from want and added in got, prefixed
+// with their line number in want or got. Lines are matched by longest
+// common subsequence, so an inserted line does not mark the rest as changed.
+func diffLines(want, got string) string {
+	a := strings.Split(want, "\n")
+	b := strings.Split(got, "\n")
+
+	// common[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j == len(b) || i < len(a) && common[i+1][j] >= common[i][j+1]:
+			fmt.Fprintf(&sb, "%4d - %s\n", i+1, a[i])
+			i++
+		default:
+			fmt.Fprintf(&sb, "%4d + %s\n", j+1, b[j])
+			j++
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,128 @@
+package surftest
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	surf "github.com/berkan-cetinkaya/surf/helpers/go"
+)
+
+// userUpdate is the -update flag many test packages define themselves;
+// registering it here checks that surftest does not claim the name
+var userUpdate = flag.Bool("update", false, "update golden files")
+
+// recorder captures failures instead of failing the test
+type recorder struct {
+	testing.TB
+	failed  bool
+	message string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failed = true
+	r.message = fmt.Sprintf(format, args...)
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+}
+
+func writeGolden(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "patch.golden")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAssertGoldenMatch(t *testing.T) {
+	p := surf.NewPatch().AddSurface("#a", "1")
+	path := writeGolden(t, p.Render())
+
+	r := &recorder{TB: t}
+	AssertGolden(r, p, path)
+	if r.failed {
+		t.Errorf("unexpected failure: %s", r.message)
+	}
+}
+
+func TestAssertGoldenMismatch(t *testing.T) {
+	path := writeGolden(t, surf.NewPatch().AddSurface("#a", "1").Render())
+
+	r := &recorder{TB: t}
+	AssertGolden(r, surf.NewPatch().AddSurface("#a", "2"), path)
+	if !r.failed {
+		t.Fatal("expected a mismatch")
+	}
+	for _, want := range []string{
+		`   2 -   <surface target="#a">1</surface>`,
+		`   2 +   <surface target="#a">2</surface>`,
+	} {
+		if !strings.Contains(r.message, want) {
+			t.Errorf("diff missing %q:\n%s", want, r.message)
+		}
+	}
+	if strings.Contains(r.message, "<d-patch>") {
+		t.Errorf("diff should only show differing lines:\n%s", r.message)
+	}
+}
+
+func TestAssertGoldenInsertedLine(t *testing.T) {
+	path := writeGolden(t, surf.NewPatch().AddSurface("#a", "1").AddSurface("#b", "2").Render())
+
+	r := &recorder{TB: t}
+	AssertGolden(r, surf.NewPatch().AddSurface("#new", "0").AddSurface("#a", "1").AddSurface("#b", "2"), path)
+	if !r.failed {
+		t.Fatal("expected a mismatch")
+	}
+	if !strings.Contains(r.message, `   2 +   <surface target="#new">0</surface>`) {
+		t.Errorf("diff missing the inserted line:\n%s", r.message)
+	}
+	if strings.Contains(r.message, `target="#a"`) || strings.Contains(r.message, `target="#b"`) {
+		t.Errorf("diff should not mark lines after the insertion:\n%s", r.message)
+	}
+}
+
+func TestAssertGoldenUpdate(t *testing.T) {
+	defer func(v bool) { *update = v }(*update)
+	*update = true
+
+	path := filepath.Join(t.TempDir(), "testdata", "patch.golden")
+	p := surf.NewPatch().AddSurface("#a", "1")
+
+	r := &recorder{TB: t}
+	AssertGolden(r, p, path)
+	if r.failed {
+		t.Fatalf("unexpected failure: %s", r.message)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != p.Render() {
+		t.Errorf("golden file not updated: %q", data)
+	}
+}
+
+func TestAssertGoldenUserUpdateFlag(t *testing.T) {
+	defer func(v bool) { *userUpdate = v }(*userUpdate)
+	*userUpdate = true
+
+	path := filepath.Join(t.TempDir(), "patch.golden")
+	p := surf.NewPatch().AddSurface("#a", "1")
+
+	r := &recorder{TB: t}
+	AssertGolden(r, p, path)
+	if r.failed {
+		t.Fatalf("unexpected failure: %s", r.message)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != p.Render() {
+		t.Errorf("golden file not updated: %q, %v", data, err)
+	}
+}
@@ -0,0 +1,92 @@
+package surf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownTarget is returned by RenderSafe when a surface uses an alias
+// that was never registered
+var ErrUnknownTarget = errors.New("surf: unknown target alias")
+
+// ErrForbiddenTarget is returned by RestrictTargets for a target outside
+// the allowlist
+var ErrForbiddenTarget = errors.New("surf: target not allowed")
+
+var (
+	targetsMu sync.RWMutex
+	targets   = make(map[string]string)
+)
+
+// RegisterTarget maps an alias (e.g. "@main") to a CSS selector.
+// The leading "@" is optional when registering.
+func RegisterTarget(alias, selector string) {
+	if !strings.HasPrefix(alias, "@") {
+		alias = "@" + alias
+	}
+
+	targetsMu.Lock()
+	defer targetsMu.Unlock()
+	targets[alias] = selector
+}
+
+// resolveTarget returns the selector for an alias, or the target unchanged
+// when it is not an alias. For a ByShadow target the host is resolved.
+func resolveTarget(target string) (string, error) {
+	if host, inner, ok := splitShadow(target); ok {
+		selector, err := resolveAlias(host)
+		if err != nil {
+			return target, err
+		}
+		return ByShadow(selector, inner), nil
+	}
+	return resolveAlias(target)
+}
+
+// resolveAlias returns the selector registered for alias, or alias
+// unchanged when it does not start with "@"
+func resolveAlias(target string) (string, error) {
+	if !strings.HasPrefix(target, "@") {
+		return target, nil
+	}
+
+	targetsMu.RLock()
+	selector, ok := targets[target]
+	targetsMu.RUnlock()
+
+	if !ok {
+		return target, fmt.Errorf("%w %q", ErrUnknownTarget, target)
+	}
+	return selector, nil
+}
+
+// RestrictTargets returns an error if any surface targets a selector not in
+// allowed. Matching is exact, after alias resolution, so "#main" does not
+// permit "#main .child". Slot surfaces are matched against "slot:<name>"
+// entries, so AddSlot("header", ...) needs "slot:header" in allowed.
+// The patch is not modified.
+func (p *Patch) RestrictTargets(allowed []string) error {
+	permitted := make(map[string]bool, len(allowed))
+	for _, selector := range allowed {
+		permitted[selector] = true
+	}
+
+	for _, s := range p.surfaces {
+		if s.Slot != "" {
+			if key := surfaceKey(s); s.Target != "" || !permitted[key] {
+				return fmt.Errorf("%w: %q", ErrForbiddenTarget, key)
+			}
+			continue
+		}
+		target, err := resolveTarget(s.Target)
+		if err != nil {
+			return err
+		}
+		if !permitted[target] {
+			return fmt.Errorf("%w: %q", ErrForbiddenTarget, target)
+		}
+	}
+	return nil
+}
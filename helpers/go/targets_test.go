@@ -0,0 +1,70 @@
+package surf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAliasResolved(t *testing.T) {
+	RegisterTarget("@main", "#main-content")
+	t.Cleanup(func() { unregisterTarget("@main") })
+
+	html, err := NewPatch().AddSurface("@main", "<p>Hi</p>").RenderSafe()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(html, `<surface target="#main-content"><p>Hi</p></surface>`) {
+		t.Errorf("alias not resolved: %s", html)
+	}
+}
+
+func TestAliasUnknown(t *testing.T) {
+	p := NewPatch().AddSurface("@missing", "x")
+
+	if _, err := p.RenderSafe(); !errors.Is(err, ErrUnknownTarget) {
+		t.Fatalf("expected ErrUnknownTarget, got %v", err)
+	}
+	if !strings.Contains(p.Render(), `target="@missing"`) {
+		t.Errorf("Render should keep unknown alias as-is: %s", p.Render())
+	}
+}
+
+func unregisterTarget(alias string) {
+	targetsMu.Lock()
+	defer targetsMu.Unlock()
+	delete(targets, alias)
+}
+
+func TestRestrictTargetsAllowed(t *testing.T) {
+	RegisterTarget("@widget", "#plugin-widget")
+	t.Cleanup(func() { unregisterTarget("@widget") })
+
+	p := NewPatch().AddSurface("@widget", "w").AppendSurface("#plugin-log", "<li>x</li>")
+	if err := p.RestrictTargets([]string{"#plugin-widget", "#plugin-log"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRestrictTargetsForbidden(t *testing.T) {
+	p := NewPatch().AddSurface("#plugin-widget", "w").AddSurface("#plugin-widget .admin", "x")
+
+	err := p.RestrictTargets([]string{"#plugin-widget"})
+	if !errors.Is(err, ErrForbiddenTarget) || !strings.Contains(err.Error(), `"#plugin-widget .admin"`) {
+		t.Errorf("expected ErrForbiddenTarget naming the target, got %v", err)
+	}
+	if len(p.Surfaces()) != 2 {
+		t.Errorf("RestrictTargets must not modify the patch")
+	}
+}
+
+func TestRestrictTargetsSlots(t *testing.T) {
+	p := NewPatch().AddSurface("#w", "ok").AddSlot("admin", "<script>steal()</script>")
+
+	if err := p.RestrictTargets([]string{"#w"}); !errors.Is(err, ErrForbiddenTarget) {
+		t.Errorf("slot outside the allowlist should be rejected, got %v", err)
+	}
+	if err := p.RestrictTargets([]string{"#w", "slot:admin"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package surf
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownComponent is returned by RenderSafe when AddComponent names a
+// component that was never registered
+var ErrUnknownComponent = errors.New("surf: unknown component")
+
+var (
+	componentsMu sync.RWMutex
+	components   = make(map[string]func(data any) (string, error))
+)
+
+// RegisterComponent registers a reusable surface template under name.
+// Registering a name again replaces the earlier renderer.
+func RegisterComponent(name string, render func(data any) (string, error)) {
+	componentsMu.Lock()
+	defer componentsMu.Unlock()
+	components[name] = render
+}
+
+// AddComponent renders the component registered as name with data and adds
+// the result as a surface. The rendered HTML is trusted, as with
+// AddTrusted. Unknown names and render errors are recorded and reported by
+// RenderSafe; no surface is added.
+func (p *Patch) AddComponent(target, name string, data any) *Patch {
+	componentsMu.RLock()
+	render, ok := components[name]
+	componentsMu.RUnlock()
+
+	if !ok {
+		return p.fail(fmt.Errorf("%w %q", ErrUnknownComponent, name))
+	}
+	content, err := render(data)
+	if err != nil {
+		return p.fail(fmt.Errorf("surf: component %q: %w", name, err))
+	}
+	return p.AddTrusted(target, content)
+}
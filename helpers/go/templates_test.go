@@ -0,0 +1,54 @@
+package surf
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func unregisterComponent(name string) {
+	componentsMu.Lock()
+	defer componentsMu.Unlock()
+	delete(components, name)
+}
+
+func TestAddComponent(t *testing.T) {
+	RegisterComponent("card", func(data any) (string, error) {
+		return fmt.Sprintf(`<div class="card">%s</div>`, escapeText(data.(string))), nil
+	})
+	t.Cleanup(func() { unregisterComponent("card") })
+
+	p := NewPatch().
+		AddComponent("#a", "card", "<first>").
+		AddComponent("#b", "card", "second")
+
+	if _, err := p.RenderSafe(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertSurfaces(t, p.Surfaces(), []Surface{
+		{Target: "#a", Content: `<div class="card">&lt;first&gt;</div>`},
+		{Target: "#b", Content: `<div class="card">second</div>`},
+	})
+}
+
+func TestAddComponentUnknown(t *testing.T) {
+	p := NewPatch().AddComponent("#a", "missing", nil)
+
+	_, err := p.RenderSafe()
+	if !errors.Is(err, ErrUnknownComponent) {
+		t.Fatalf("expected ErrUnknownComponent, got %v", err)
+	}
+	if len(p.Surfaces()) != 0 {
+		t.Errorf("no surface should be added, got %v", p.Surfaces())
+	}
+}
+
+func TestAddComponentRenderError(t *testing.T) {
+	boom := errors.New("boom")
+	RegisterComponent("broken", func(any) (string, error) { return "", boom })
+	t.Cleanup(func() { unregisterComponent("broken") })
+
+	if _, err := NewPatch().AddComponent("#a", "broken", nil).RenderSafe(); !errors.Is(err, boom) {
+		t.Errorf("expected render error, got %v", err)
+	}
+}
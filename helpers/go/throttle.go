@@ -0,0 +1,126 @@
+package surf
+
+import (
+	"sync"
+	"time"
+)
+
+// Throttler buffers patches for a streaming session and passes them to
+// send at most once per interval, coalescing surfaces and directives queued
+// in between as a Coalescer does. Sends never overlap. send is called
+// without locks held, but it should return once the session has ended, for
+// example by selecting on Multiplexer.Done, or the queue stops draining.
+// It is safe for concurrent use.
+type Throttler struct {
+	interval time.Duration
+	send     func(*Patch) error
+	clock    clock
+
+	mu        sync.Mutex
+	queue     *Coalescer
+	pending   bool
+	sending   bool
+	flushed   bool
+	lastFlush time.Time
+	timer     timer
+	err       error
+	closed    bool
+}
+
+// NewThrottler creates a Throttler that flushes to send, such as a
+// Multiplexer channel or an SSE writer, at most once per interval
+func NewThrottler(interval time.Duration, send func(*Patch) error) *Throttler {
+	return &Throttler{
+		interval: interval,
+		send:     send,
+		clock:    realClock{},
+		queue:    NewCoalescer(),
+	}
+}
+
+// Add queues the surfaces and directives of p. They are sent at once if the
+// interval has passed since the last flush, otherwise when it does. Add
+// returns the error of an earlier send, after which the throttler stops
+// sending.
+func (t *Throttler) Add(p *Patch) error {
+	t.mu.Lock()
+	if t.err != nil || t.closed || (p.IsEmpty() && p.err == nil) {
+		err := t.err
+		t.mu.Unlock()
+		return err
+	}
+	t.queue.Add(p)
+	t.pending = true
+	return t.dispatch()
+}
+
+// Close sends any queued surfaces immediately and stops the throttler.
+// If a send is in progress, the queue is flushed as soon as it returns.
+func (t *Throttler) Close() error {
+	t.mu.Lock()
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	t.closed = true
+	return t.dispatch()
+}
+
+func (t *Throttler) flushTimer() {
+	t.mu.Lock()
+	t.timer = nil
+	t.dispatch()
+}
+
+// dispatch sends the queued patch while one is due: immediately once
+// closed, otherwise when the interval since the last flush has passed, in
+// which case a timer is set for later. Only one goroutine sends at a time;
+// the others leave the queue to it. t.mu must be held and is released.
+func (t *Throttler) dispatch() error {
+	defer t.mu.Unlock()
+
+	for t.pending && !t.sending && t.err == nil {
+		if wait := t.interval - t.clock.Now().Sub(t.lastFlush); !t.closed && t.flushed && wait > 0 {
+			if t.timer == nil {
+				t.timer = t.clock.AfterFunc(wait, t.flushTimer)
+			}
+			break
+		}
+
+		p := t.queue.Flush()
+		t.pending = false
+		t.sending = true
+		t.flushed = true
+		t.lastFlush = t.clock.Now()
+
+		t.mu.Unlock()
+		err := t.send(p)
+		t.mu.Lock()
+
+		t.sending = false
+		if err != nil {
+			t.err = err
+		}
+	}
+	return t.err
+}
+
+// clock abstracts time for the Throttler so tests can control it
+type clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) timer
+}
+
+type timer interface {
+	Stop() bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) timer {
+	return time.AfterFunc(d, f)
+}
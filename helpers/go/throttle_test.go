@@ -0,0 +1,183 @@
+package surf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock runs AfterFunc callbacks when Advance passes their deadline
+type fakeClock struct {
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+func (t *fakeTimer) Stop() bool {
+	wasActive := !t.stopped
+	t.stopped = true
+	return wasActive
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) timer {
+	t := &fakeTimer{at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+	for _, t := range c.timers {
+		if !t.stopped && !t.at.After(c.now) {
+			t.stopped = true
+			t.f()
+		}
+	}
+}
+
+func newTestThrottler(interval time.Duration) (*Throttler, *fakeClock, *[]*Patch) {
+	var sent []*Patch
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	th := NewThrottler(interval, func(p *Patch) error {
+		sent = append(sent, p)
+		return nil
+	})
+	th.clock = clock
+	return th, clock, &sent
+}
+
+func TestThrottlerFlushFrequency(t *testing.T) {
+	th, clock, sent := newTestThrottler(time.Second)
+
+	// The first patch goes out at once, the next ones wait for the interval
+	th.Add(NewPatch().AddSurface("#a", "1"))
+	if len(*sent) != 1 {
+		t.Fatalf("first patch should be sent immediately, sent %d", len(*sent))
+	}
+
+	th.Add(NewPatch().AddSurface("#b", "2"))
+	clock.Advance(500 * time.Millisecond)
+	th.Add(NewPatch().AddSurface("#c", "3"))
+	if len(*sent) != 1 {
+		t.Fatalf("patches within the interval should be held, sent %d", len(*sent))
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if len(*sent) != 2 {
+		t.Fatalf("held patches should be sent after the interval, sent %d", len(*sent))
+	}
+	assertSurfaces(t, (*sent)[1].Surfaces(), []Surface{
+		{Target: "#b", Content: "2"},
+		{Target: "#c", Content: "3"},
+	})
+
+	clock.Advance(time.Second)
+	if len(*sent) != 2 {
+		t.Errorf("nothing should be sent without queued surfaces, sent %d", len(*sent))
+	}
+}
+
+func TestThrottlerCoalescesReplaces(t *testing.T) {
+	th, clock, sent := newTestThrottler(time.Second)
+	th.Add(NewPatch().AddSurface("#count", "0"))
+
+	th.Add(NewPatch().AddSurface("#count", "1").AppendSurface("#log", "a"))
+	th.Add(NewPatch().AddSurface("#count", "2").AppendSurface("#log", "b"))
+	clock.Advance(time.Second)
+
+	if len(*sent) != 2 {
+		t.Fatalf("expected 2 flushes, got %d", len(*sent))
+	}
+	assertSurfaces(t, (*sent)[1].Surfaces(), []Surface{
+		{Target: "#log", Content: "a", Mode: ModeAppend},
+		{Target: "#count", Content: "2"},
+		{Target: "#log", Content: "b", Mode: ModeAppend},
+	})
+}
+
+func TestThrottlerCloseFlushesImmediately(t *testing.T) {
+	th, _, sent := newTestThrottler(time.Minute)
+	th.Add(NewPatch().AddSurface("#a", "1"))
+	th.Add(NewPatch().AddSurface("#a", "2"))
+
+	if err := th.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*sent) != 2 {
+		t.Fatalf("Close should flush queued surfaces, sent %d", len(*sent))
+	}
+	assertSurfaces(t, (*sent)[1].Surfaces(), []Surface{{Target: "#a", Content: "2"}})
+
+	th.Add(NewPatch().AddSurface("#a", "3"))
+	if len(*sent) != 2 {
+		t.Errorf("nothing should be sent after Close, sent %d", len(*sent))
+	}
+}
+
+func TestThrottlerSendError(t *testing.T) {
+	boom := errors.New("boom")
+	th := NewThrottler(time.Second, func(*Patch) error { return boom })
+	th.clock = &fakeClock{}
+
+	if err := th.Add(NewPatch().AddSurface("#a", "1")); !errors.Is(err, boom) {
+		t.Fatalf("expected send error, got %v", err)
+	}
+	if err := th.Add(NewPatch().AddSurface("#a", "2")); !errors.Is(err, boom) {
+		t.Errorf("later adds should report the send error, got %v", err)
+	}
+}
+
+func TestThrottlerKeepsDirectives(t *testing.T) {
+	th, clock, sent := newTestThrottler(time.Second)
+	th.Add(NewPatch().AddSurface("#a", "1"))
+	th.Add(Ack("op-1"))
+	clock.Advance(time.Second)
+
+	if len(*sent) != 2 {
+		t.Fatalf("an ack-only patch should be sent, sent %d", len(*sent))
+	}
+	if html := (*sent)[1].Render(); !strings.Contains(html, `<ack id="op-1"></ack>`) {
+		t.Errorf("missing ack in:\n%s", html)
+	}
+}
+
+func TestThrottlerBlockedSendDoesNotHoldLock(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	th := NewThrottler(time.Second, func(*Patch) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+	th.clock = &fakeClock{}
+
+	go th.Add(NewPatch().AddSurface("#a", "1"))
+	<-started
+
+	done := make(chan struct{})
+	go func() {
+		th.Add(NewPatch().AddSurface("#a", "2"))
+		th.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Add and Close should not wait for a blocked send")
+	}
+
+	// The queued surface is flushed once the blocked send returns
+	release <- struct{}{}
+	<-started
+	release <- struct{}{}
+}
@@ -0,0 +1,37 @@
+package surf
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AddSurfaceTimed adds a surface with content from gen, recording how long
+// gen took. The response helpers report every timed surface in a
+// Server-Timing header, as "surfN;desc=<target>;dur=<ms>".
+func (p *Patch) AddSurfaceTimed(target string, gen func() string) *Patch {
+	start := time.Now()
+	content := gen()
+
+	return p.add(Surface{
+		Target:   target,
+		Content:  strictText(content),
+		duration: time.Since(start),
+		timed:    true,
+	})
+}
+
+// serverTiming returns the Server-Timing header value, or "" when no
+// surface is timed
+func (p *Patch) serverTiming() string {
+	var metrics []string
+	for _, s := range p.surfaces {
+		if !s.timed {
+			continue
+		}
+		desc := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s.Target)
+		ms := float64(s.duration) / float64(time.Millisecond)
+		metrics = append(metrics, fmt.Sprintf(`surf%d;desc="%s";dur=%.3f`, len(metrics)+1, desc, ms))
+	}
+	return strings.Join(metrics, ", ")
+}
@@ -0,0 +1,51 @@
+package surf
+
+import (
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddSurfaceTimed(t *testing.T) {
+	p := NewPatch().
+		AddSurfaceTimed("#header", func() string { return "<h1>Hi</h1>" }).
+		AddSurface("#plain", "untimed").
+		AddSurfaceTimed(`[data-slot="feed"]`, func() string {
+			time.Sleep(5 * time.Millisecond)
+			return "<ul></ul>"
+		})
+
+	rec := httptest.NewRecorder()
+	if err := p.WriteResponse(rec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	header := rec.Header().Get("Server-Timing")
+	metrics := strings.Split(header, ", ")
+	if len(metrics) != 2 {
+		t.Fatalf("expected 2 metrics, got %q", header)
+	}
+
+	first := regexp.MustCompile(`^surf1;desc="#header";dur=\d+\.\d{3}$`)
+	second := regexp.MustCompile(`^surf2;desc="\[data-slot=\\"feed\\"\]";dur=(\d+\.\d{3})$`)
+	if !first.MatchString(metrics[0]) {
+		t.Errorf("unexpected first metric %q", metrics[0])
+	}
+	if !second.MatchString(metrics[1]) {
+		t.Errorf("unexpected second metric %q", metrics[1])
+	}
+	if !strings.Contains(rec.Body.String(), "<ul></ul>") {
+		t.Errorf("timed content missing: %s", rec.Body.String())
+	}
+}
+
+func TestNoServerTimingWithoutTimedSurfaces(t *testing.T) {
+	rec := httptest.NewRecorder()
+	_ = NewPatch().AddSurface("#a", "1").WriteResponse(rec)
+
+	if rec.Header().Get("Server-Timing") != "" {
+		t.Errorf("unexpected Server-Timing header")
+	}
+}